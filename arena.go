@@ -0,0 +1,129 @@
+package gauntletbot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// how many map updates can wait for the arena, or for a bot to take them from it, before we start dropping them
+const arenaBacklog = 1024
+
+// a piece of the map one bot has seen, to pass on to the others
+type mapUpdate struct {
+	from  *Bot
+	event Event // NearbyWalls, NearbyFloors or NearbyItem
+}
+
+// Arena lets several bots in one process share what they see of the map.  Each bot publishes the
+// walls, floors and items it hears about; a single goroutine (Run) fans them out to every other bot,
+// which applies them through the same locked setters as its own read loop, so a teammate coming across
+// our key tells us where it is.  Our own player state, enemies, our own key and the exit stay with each bot
+type Arena struct {
+	updates chan mapUpdate
+	mu      sync.Mutex
+	inboxes map[*Bot]chan Event
+}
+
+// NewArena makes an arena with no bots in it.  Join bots to it before running them
+func NewArena() *Arena {
+	return &Arena{updates: make(chan mapUpdate, arenaBacklog), inboxes: make(map[*Bot]chan Event)}
+}
+
+// Join has a bot share its view of the map with the others in the arena, and see theirs
+func (a *Arena) Join(b *Bot) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	inbox := make(chan Event, arenaBacklog)
+	a.inboxes[b] = inbox
+	b.arena = a
+	b.shared = inbox
+}
+
+// Run passes updates from each bot to all the others until the context is cancelled
+func (a *Arena) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case u := <-a.updates:
+			a.mu.Lock()
+			for bot, inbox := range a.inboxes {
+				if bot == u.from {
+					continue
+				}
+				select {
+				case inbox <- u.event:
+				default:
+					// they're not keeping up; this is only a teammate's view, so better lost than holding everyone up
+				}
+			}
+			a.mu.Unlock()
+		}
+	}
+}
+
+// pass something we've seen of the map on to the rest of the arena, if we're in one
+func (b *Bot) share(e Event) {
+	if b.arena == nil {
+		return
+	}
+	select {
+	case b.arena.updates <- mapUpdate{from: b, event: e}:
+	default:
+	}
+}
+
+// apply what the rest of the arena has seen until the context is cancelled
+func (b *Bot) listen(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-b.shared:
+			switch e := e.(type) {
+			case NearbyWalls:
+				for _, wall := range e.Walls {
+					b.setWall(wall.X, wall.Y)
+				}
+			case NearbyFloors:
+				for _, floor := range e.Floors {
+					b.setFloor(floor.X, floor.Y)
+				}
+			case NearbyItem:
+				// a teammate's sighting of someone else's key may be of ours
+				if !b.noteKey(e) {
+					b.addItem(e.Type, e.Loc.X, e.Loc.Y)
+				}
+			}
+		}
+	}
+}
+
+// BotSpec is one of the bots to run for -bots
+type BotSpec struct {
+	Class string
+	Name  string
+}
+
+// ParseBots reads a comma separated list of class:name pairs, e.g. warrior:alice,elf:bob
+func ParseBots(list string) ([]BotSpec, error) {
+	var specs []BotSpec
+	names := make(map[string]bool)
+	for _, pair := range strings.Split(list, ",") {
+		if pair == "" {
+			continue
+		}
+		class, name, ok := strings.Cut(pair, ":")
+		if !ok || class == "" || name == "" {
+			return nil, fmt.Errorf("bad bot %q, want class:name", pair)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("more than one bot called %s", name)
+		}
+		names[name] = true
+		specs = append(specs, BotSpec{Class: class, Name: name})
+	}
+	return specs, nil
+}
@@ -0,0 +1,113 @@
+package gauntletbot
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestArenaShares(t *testing.T) {
+	arena := NewArena()
+	var bots []*Bot
+	for _, name := range []string{"warrior", "elf", "wizard"} {
+		b, _ := newTestBot(func(cfg *Config) { cfg.Name = name })
+		arena.Join(b)
+		bots = append(bots, b)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go arena.Run(ctx)
+	for _, b := range bots {
+		go b.listen(ctx)
+	}
+
+	seer := bots[0]
+	seer.handleMessage("playerupdate:100,100,10,10,False")
+	seer.handleMessage("nearbywalls:0,0,8,0")
+	seer.handleMessage("nearbyfloors:0,8")
+	seer.handleMessage("nearbyitem:ammo,40,40")
+	seer.handleMessage("nearbyitem:redkey,60,60") // the warrior's own key, which is no one else's business
+	seer.handleMessage("nearbyplayer:orc,grunt,140,100")
+	seer.handleMessage("exit:200,200")
+
+	for _, b := range bots[1:] {
+		for give := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+			s := b.Snapshot()
+			if s.Walls == 2 && s.Floors == 1 && s.Items["ammo"] == 1 {
+				break
+			}
+			if time.Now().After(give) {
+				t.Fatalf("%s only learnt %d walls, %d floors and %v from the arena", b.cfg.Name, s.Walls, s.Floors, s.Items)
+			}
+		}
+		s := b.Snapshot()
+		if s.Items["redkey"] != 0 || len(s.Enemies) != 0 || s.Player.Exit != nil || s.Player.Loc != (Loc{}) {
+			t.Errorf("%s was told more than the map: %+v", b.cfg.Name, s)
+		}
+	}
+	if s := seer.Snapshot(); s.Walls != 2 || s.Items["ammo"] != 1 {
+		t.Errorf("the warrior's own view changed to %d walls and %v", s.Walls, s.Items)
+	}
+}
+
+func TestArenaSharesKeys(t *testing.T) {
+	arena := NewArena()
+	warrior, _ := newTestBot(func(cfg *Config) { cfg.Name = "warrior" })
+	elf, _ := newTestBot(func(cfg *Config) { cfg.Name = "elf" })
+	arena.Join(warrior)
+	arena.Join(elf)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go arena.Run(ctx)
+	go warrior.listen(ctx)
+	go elf.listen(ctx)
+
+	// each comes across the other's key
+	warrior.handleMessage("nearbyitem:" + elf.myKeyName() + ",60,60")
+	elf.handleMessage("nearbyitem:" + warrior.myKeyName() + ",80,20")
+	for _, tt := range []struct {
+		b    *Bot
+		want Loc
+	}{
+		{elf, Loc{X: 60, Y: 60}},
+		{warrior, Loc{X: 80, Y: 20}},
+	} {
+		for give := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+			if key := tt.b.snapshotPlayer().MyKey; key != nil {
+				if *key != tt.want {
+					t.Errorf("%s was told its key is at %v, want %v", tt.b.cfg.Name, *key, tt.want)
+				}
+				break
+			}
+			if time.Now().After(give) {
+				t.Fatalf("%s never learnt where its key is from its teammate", tt.b.cfg.Name)
+			}
+		}
+		if s := tt.b.Snapshot(); s.Items[tt.b.myKeyName()] != 0 {
+			t.Errorf("%s keeps its own key as an item too: %v", tt.b.cfg.Name, s.Items)
+		}
+	}
+}
+
+func TestParseBots(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []BotSpec
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"warrior:alice", []BotSpec{{Class: "warrior", Name: "alice"}}, false},
+		{"warrior:alice,elf:bob,", []BotSpec{{Class: "warrior", Name: "alice"}, {Class: "elf", Name: "bob"}}, false},
+		{"warrior:alice,elf:alice", nil, true},
+		{"warrior", nil, true},
+		{"warrior:", nil, true},
+		{":alice", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBots(tt.list)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseBots(%q) = %v, %v, want %v and an error %v", tt.list, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+// Package gauntletbot plays MSGauntlet.  Make a Bot from a Config with NewBot and Run it; the command
+// in cmd/gauntletBot does just that from flags
+package gauntletbot
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Bot is a single player: its view of the game, its connection to the server and its settings.
+// Several can run side by side in one process
+type Bot struct {
+	cfg    Config
+	conn   Transport // what the loops talk to the server through: udp, perhaps wrapped in a capture
+	udp    *net.UDPConn
+	sender Sender // where each tick's commands go: conn, or the log in a dry run, behind the rate limit if there is one
+	direct Sender // the same without the rate limit, for joins, which must get through
+	rng    *rand.Rand
+	clock  Clock // where the time comes from, Config.Clock or the real one
+	scorer Scorer
+	state  State
+
+	metrics *metrics // counters for -metricsaddr
+
+	arena  *Arena     // the bots we share the map with, if any
+	shared chan Event // what they've seen, for listen to apply
+
+	tracing atomic.Bool // log the reasoning behind every decision
+
+	tile        atomic.Int64 // size of a map tile, learnt from the spacing of walls and floors
+	tileSettled bool         // whether we've stopped learning the tile size, only touched by the read loop
+	spacing     map[int]int  // how often we've seen each gap between neighbouring tiles
+
+	wallMutex   sync.Mutex
+	floorMutex  sync.Mutex
+	itemMutex   sync.Mutex
+	teamMutex   sync.Mutex
+	motionMutex sync.Mutex
+	losMutex    sync.Mutex
+	overMutex   sync.Mutex
+	playerMutex sync.Mutex // guards Player and Updated, which the read loop writes
+	connMutex   sync.Mutex // guards conn, udp and connected, which the watchdog replaces on reconnecting
+	connected   time.Time
+	enemyMutex  sync.Mutex // guards Enemies
+	losCache    map[sightLine]sighting
+	wallIndex   map[Loc]map[Loc]time.Time   // walls by the losBucket they're in, with when we last heard of each, under wallMutex
+	cellSeen    map[Loc]time.Time           // when we last heard about each wall or floor, under both map locks.  Only kept with MaxMapCells
+	sightings   map[string]*sightingHistory // recent positions of each enemy, under motionMutex
+
+	// combat bookkeeping, only touched by the write loop
+	shotsWithoutEffect int // consecutive shots after which the enemy was exactly where it had been
+	lastShotTarget     *Loc
+	repositioning      int // ticks of repositioning left
+	repositionDir      string
+	alignment          []bool // whether each of our recent shots was lined up on its target
+
+	// supply runs, only touched by the write loop.  Once started they carry on until we're comfortably restocked
+	seekingFood bool
+	seekingAmmo bool
+
+	// whether the exit needs the key, learnt by standing on it without one.  Only touched by the write loop
+	exitLocked  bool
+	atExitSince time.Time
+
+	warmupTicks int  // ticks spent warming up so far, only touched by the write loop
+	warmedUp    bool // whether we've finished warming up
+
+	headingFor *Item // the item we went for last tick.  Kept by the write loop and cleared by a new round, under goalMutex
+
+	// reacting to damage
+	damageHooks []func(prev int, curr int) // OnDamage subscribers
+	strafing    atomic.Int32               // ticks of strafing left, set by the read loop when we take damage
+	strafeSide  int                        // which way we're strafing, +2 or -2 compass points from the enemy, only touched by the write loop
+
+	// getting unstuck, only touched by the write loop
+	moveGoal   *Loc  // where the last move we sent was to
+	recentLocs []Loc // where we've been while trying to get somewhere else
+	unsticking int   // ticks of jogging sideways left
+	unstickDir string
+
+	explored map[Loc]bool // tiles we've stood on while exploring, so aren't worth heading for again.  Only touched by the write loop
+
+	// the commands the write loop has decided on this tick, only touched by the write loop
+	planning bool
+	plan     []string
+
+	pathCosts map[sightLine]float64 // walking distances worked out this tick, only touched by the write loop
+	tickSnap  *Snapshot             // the game as this tick first saw it, once something has asked.  Only touched by the write loop
+
+	spawnChecked map[Loc]time.Time // when we last went to a spawn point and found nothing there, only touched by the write loop
+
+	script   *scriptRun // a fixed list of objectives to follow instead of our own, if configured
+	strategy Strategy   // decides what we go after each tick, only used by the write loop
+
+	// giving up on the key, and the item we were heading for.  The write loop keeps these and a new round
+	// clears them, under goalMutex
+	goalMutex  sync.Mutex
+	keySince   time.Time // when we started looking for our key this time
+	keyGivenUp bool
+}
+
+// NewBot sets up a bot with an empty view of the game.  Nothing is sent until Connect or Run
+func NewBot(cfg Config) *Bot {
+	if cfg.Clock == nil {
+		cfg.Clock = realClock{}
+	}
+	if cfg.TickMs < minTickMs {
+		Warnf("a %dms tick would flood the server, using %dms", cfg.TickMs, minTickMs)
+		cfg.TickMs = minTickMs
+	}
+	b := &Bot{
+		cfg:   cfg,
+		rng:   rand.New(rand.NewSource(cfg.Seed)),
+		clock: cfg.Clock,
+		state: State{
+			Floor:     make(map[int]map[int]bool),
+			Walls:     make(map[int]map[int]bool),
+			Teammates: make(map[string]Item),
+			Enemies:   make(map[string]Item),
+			Items:     make(map[string][]Item),
+			Spawns:    make(map[string]map[Loc]time.Time),
+		},
+		losCache:      make(map[sightLine]sighting),
+		wallIndex:     make(map[Loc]map[Loc]time.Time),
+		cellSeen:      make(map[Loc]time.Time),
+		sightings:     make(map[string]*sightingHistory),
+		metrics:       newMetrics(cfg.Clock),
+		spawnChecked:  make(map[Loc]time.Time),
+		pathCosts:     make(map[sightLine]float64),
+		explored:      make(map[Loc]bool),
+		repositionDir: "ne",
+		exitLocked:    cfg.ExitNeedsKey,
+		warmedUp:      cfg.WarmupTicks == 0 && cfg.WarmupCoverage == 0,
+	}
+	if len(cfg.Script) > 0 {
+		b.script = &scriptRun{Steps: cfg.Script, Timeout: cfg.ScriptTimeout}
+	}
+	b.strategy = b.newStrategy(cfg.Strategy)
+	b.direct = connSender{b}
+	if cfg.DryRun {
+		b.direct = logSender{}
+	}
+	b.sender = b.direct
+	if cfg.MaxPPS > 0 {
+		b.sender = newRateLimiter(b.sender, cfg.MaxPPS, b.clock)
+	}
+	b.scorer = cfg.Scorer
+	if b.scorer == nil {
+		b.scorer = b.defaultScore
+	}
+	b.tracing.Store(cfg.Trace)
+	b.tile.Store(int64(cfg.TileSize))
+	b.spacing = make(map[int]int)
+	return b
+}
+
+// Connect dials the server and asks to join the game
+func (b *Bot) Connect() error {
+	b.connMutex.Lock()
+	err := b.reconnect(&b.udp)
+	b.connMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	b.join(b.cfg.Name)
+	return nil
+}
+
+// dial a fresh connection to the server in place of *conn, closing the old one if there was one.
+// Must be called with connMutex held, as it also swaps the transport the loops use
+func (b *Bot) reconnect(conn **net.UDPConn) error {
+	s, err := net.ResolveUDPAddr(b.cfg.Network, hostPort(b.cfg.Host, b.cfg.Port))
+	if err != nil {
+		return err
+	}
+	fresh, err := net.DialUDP(b.cfg.Network, nil, s)
+	if err != nil {
+		return err
+	}
+	if *conn != nil {
+		(*conn).Close()
+	}
+	*conn = fresh
+	b.conn = fresh
+	if b.cfg.Capture != nil {
+		b.conn = newCapture(b.conn, b.cfg.Capture, b.clock)
+	}
+	if b.cfg.Record != nil {
+		b.conn = newRecorder(b.conn, b.cfg.Record, b.clock)
+	}
+	b.connected = b.clock.Now()
+	Infof("Connected to %s", fresh.RemoteAddr())
+	return nil
+}
+
+// the host:port to dial.  JoinHostPort brackets IPv6 literals, so take the brackets off any the host was given with
+func hostPort(host string, port int) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// the connection the loops should be using right now
+func (b *Bot) transport() Transport {
+	b.connMutex.Lock()
+	defer b.connMutex.Unlock()
+	return b.conn
+}
+
+// if the server goes quiet for ReconnectTimeout, assume it's restarted or lost us: dial it again and rejoin.
+// Runs until the context is cancelled
+func (b *Bot) watchdog(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		b.connMutex.Lock()
+		heard := b.lastUpdate()
+		if b.connected.After(heard) {
+			heard = b.connected
+		}
+		if b.clock.Now().Sub(heard) < b.cfg.ReconnectTimeout {
+			b.connMutex.Unlock()
+			continue
+		}
+		Warnf("Nothing from the server for %s, reconnecting", b.cfg.ReconnectTimeout)
+		err := b.reconnect(&b.udp)
+		b.connMutex.Unlock()
+		if err != nil {
+			Errorf("Reconnect failed: %v", err)
+			continue
+		}
+		b.metrics.countReconnect()
+		b.join(b.cfg.Name)
+	}
+}
+
+// replace a connection that's gone for good with a fresh one and join again
+func (b *Bot) redial() error {
+	b.connMutex.Lock()
+	err := b.reconnect(&b.udp)
+	b.connMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	b.metrics.countReconnect()
+	b.join(b.cfg.Name)
+	return nil
+}
+
+// Run plays the game until the context is cancelled or the configured maximum runtime passes,
+// connecting first if that hasn't been done yet.  The connection is closed on return
+func (b *Bot) Run(ctx context.Context) error {
+	if b.transport() == nil {
+		if err := b.Connect(); err != nil {
+			return err
+		}
+	}
+	defer func() { b.transport().Close() }()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if b.cfg.ReconnectTimeout > 0 {
+		go b.watchdog(ctx)
+	}
+	if b.cfg.MetricsAddr != "" {
+		go b.serveMetrics(ctx, b.cfg.MetricsAddr)
+	}
+	if b.shared != nil {
+		go b.listen(ctx)
+	}
+	var deadline time.Time
+	if b.cfg.MaxRuntime > 0 {
+		deadline = b.clock.Now().Add(b.cfg.MaxRuntime)
+	}
+	go b.readLoop(ctx, cancel) // background thread to capture and parse game state messages from server
+	b.writeLoop(ctx, deadline)
+	return nil
+}
+
+// a consistent copy of our player, for the write loop to work from rather than the fields the read loop is updating
+func (b *Bot) snapshotPlayer() Player {
+	b.playerMutex.Lock()
+	defer b.playerMutex.Unlock()
+	return b.state.Player
+}
+
+// when we last had a playerupdate
+func (b *Bot) lastUpdate() time.Time {
+	b.playerMutex.Lock()
+	defer b.playerMutex.Unlock()
+	return b.state.Updated
+}
+
+// Player is our own player as the server last described it
+func (b *Bot) Player() Player {
+	return b.snapshotPlayer()
+}
+
+// ObjectivesMet reports whether we've done what we came here to do
+func (b *Bot) ObjectivesMet() bool {
+	return b.snapshotPlayer().HasKey
+}
+
+// Override makes the bot pursue the given goal ("goto" a location, or "fight") instead of
+// choosing its own target, until the ttl runs out or ClearOverride is called
+func (b *Bot) Override(goal string, loc Loc, ttl time.Duration) error {
+	if goal != "goto" && goal != "fight" {
+		return fmt.Errorf("unknown override goal %q", goal)
+	}
+	b.overMutex.Lock()
+	defer b.overMutex.Unlock()
+	b.state.Override = &Override{Goal: goal, Loc: loc, Until: b.clock.Now().Add(ttl)}
+	return nil
+}
+
+// ClearOverride hands target selection back to the bot
+func (b *Bot) ClearOverride() {
+	b.overMutex.Lock()
+	defer b.overMutex.Unlock()
+	b.state.Override = nil
+}
+
+// the override in force right now, if any.  Expired ones are dropped
+func (b *Bot) activeOverride() *Override {
+	b.overMutex.Lock()
+	defer b.overMutex.Unlock()
+	if b.state.Override != nil && !b.clock.Now().Before(b.state.Override.Until) {
+		Infof("Override expired")
+		b.state.Override = nil
+	}
+	if b.state.Override == nil {
+		return nil
+	}
+	o := *b.state.Override
+	return &o
+}
@@ -0,0 +1,255 @@
+package gauntletbot
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJoinKeyExitRoundTrip(t *testing.T) {
+	s := newMockServer(t)
+	b := runTestBot(t, s, nil)
+
+	s.expect("requestjoin:", func(name string) bool { return name == "warrior" })
+	s.send("playerjoined:warrior,1,0,0", "playerupdate:0,0,3,5,False", "nearbyitem:redkey,40,0")
+	s.expect("moveto:", func(params string) bool {
+		to := movetoLoc(params)
+		return to.X > 0 && to.Y == 0
+	})
+
+	// picked it up, and now we can see the exit
+	s.send("playerupdate:40,0,3,5,True", "exit:40,60")
+	s.expect("moveto:", func(params string) bool {
+		to := movetoLoc(params)
+		return to.X == 40 && to.Y > 0
+	})
+	if !b.ObjectivesMet() {
+		t.Error("have the key but objectives not met")
+	}
+}
+
+// discards whatever it's sent
+type nopSender struct{}
+
+func (nopSender) Send([]byte) error { return nil }
+
+// run under -race: the write loop's view of the player, key and exit must only come through snapshotPlayer
+func TestDecisionsRaceFree(t *testing.T) {
+	b, _ := newTestBot(nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			b.handleMessage("playerupdate:10,10,3,5,False")
+			b.handleMessage("exit:100,100")
+			b.handleMessage("nearbyitem:redkey,50,50")
+			b.handleMessage("roundstart")
+		}
+	}()
+	ts := &tickState{dir: "ne", target: "key", shooter: NewShooter(0)}
+	for i := 0; i < 200; i++ {
+		b.tick(nopSender{}, ts)
+	}
+	<-done
+}
+
+// a Transport that never hears from the server, only counting what we write, until it's closed
+type silentTransport struct {
+	writes    atomic.Int32
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newSilentTransport() *silentTransport {
+	return &silentTransport{closed: make(chan struct{})}
+}
+
+func (s *silentTransport) Read([]byte) (int, error) {
+	<-s.closed
+	return 0, net.ErrClosed
+}
+
+func (s *silentTransport) Write(p []byte) (int, error) {
+	s.writes.Add(1)
+	return len(p), nil
+}
+
+func (s *silentTransport) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return nil
+}
+
+// wait for the bot to write n more datagrams than it had, failing the test if it doesn't
+func (s *silentTransport) waitWrites(t *testing.T, n int32) {
+	t.Helper()
+	want := s.writes.Load() + n
+	for give := time.Now().Add(5 * time.Second); s.writes.Load() < want; time.Sleep(time.Millisecond) {
+		if time.Now().After(give) {
+			t.Fatalf("the bot stopped writing, %d datagrams sent", s.writes.Load())
+		}
+	}
+}
+
+func TestRunMaxRuntime(t *testing.T) {
+	b, clock := newTestBot(func(cfg *Config) {
+		cfg.MaxRuntime = 10 * time.Second
+		cfg.TickMs = minTickMs
+		cfg.ReconnectTimeout = 0
+	})
+	conn := newSilentTransport()
+	b.conn = conn
+	done := make(chan error)
+	go func() { done <- b.Run(context.Background()) }()
+	conn.waitWrites(t, 2)
+
+	// not there yet, so still playing
+	clock.Advance(9 * time.Second)
+	conn.waitWrites(t, 2)
+	select {
+	case <-done:
+		t.Fatal("stopped before MaxRuntime")
+	default:
+	}
+
+	clock.Advance(2 * time.Second)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() = %v at the deadline, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("still running after MaxRuntime")
+	}
+	select {
+	case <-conn.closed:
+	default:
+		t.Error("connection left open after reaching MaxRuntime")
+	}
+}
+
+func TestOverrideExpires(t *testing.T) {
+	b, clock := newTestBot(nil)
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	if err := b.Override("dance", Loc{}, time.Second); err == nil {
+		t.Error("accepted an override to dance")
+	}
+	if err := b.Override("goto", Loc{X: 300, Y: 100}, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	ts := &tickState{dir: "ne", target: "key", shooter: NewShooter(0)}
+	clock.Advance(4 * time.Second)
+	b.tick(nopSender{}, ts)
+	if ts.target != "override" || b.moveGoal == nil || b.moveGoal.X <= 100 {
+		t.Fatalf("went for %s to %v with the override in force, want override towards (300,100)", ts.target, b.moveGoal)
+	}
+
+	// "fight" is the enemy target under another name
+	if err := b.Override("fight", Loc{}, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.strategy.Decide(b.Snapshot()).Goal; got != "enemy" {
+		t.Errorf("went for %s told to fight, want enemy", got)
+	}
+
+	clock.Advance(5 * time.Second)
+	if o := b.activeOverride(); o != nil {
+		t.Fatalf("override %+v still in force after its ttl", *o)
+	}
+	b.tick(nopSender{}, ts)
+	if ts.target == "override" {
+		t.Error("still following the override after it expired")
+	}
+
+	if err := b.Override("goto", Loc{X: 300, Y: 100}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	b.ClearOverride()
+	if o := b.activeOverride(); o != nil {
+		t.Errorf("override %+v still in force after ClearOverride", *o)
+	}
+}
+
+func TestWatchdogReconnects(t *testing.T) {
+	s := newMockServer(t)
+	b, clock := newTestBot(func(cfg *Config) {
+		cfg.Host = "127.0.0.1"
+		cfg.Port = s.port()
+		cfg.Name = "warrior"
+		cfg.ReconnectTimeout = 5 * time.Second
+	})
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { b.transport().Close() }()
+	s.expect("requestjoin:", nil)
+	first := b.transport()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.watchdog(ctx)
+
+	// quiet, but not for long enough.  The watchdog looks once a second
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	clock.Advance(4 * time.Second)
+	time.Sleep(1200 * time.Millisecond)
+	if b.transport() != first {
+		t.Fatal("reconnected before ReconnectTimeout")
+	}
+
+	clock.Advance(2 * time.Second)
+	s.expect("requestjoin:", func(name string) bool { return name == "warrior" })
+	if b.transport() == first {
+		t.Error("rejoined on the same connection, want a fresh one")
+	}
+	b.metrics.mu.Lock()
+	defer b.metrics.mu.Unlock()
+	if b.metrics.reconnects != 1 {
+		t.Errorf("counted %d reconnects, want 1", b.metrics.reconnects)
+	}
+}
+
+// run under -race: controllers override the bot from their own goroutines while it plays
+func TestOverrideRaceFree(t *testing.T) {
+	b, clock := newTestBot(nil)
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			if err := b.Override("goto", Loc{X: 100 + i, Y: 300}, time.Second); err != nil {
+				t.Error(err)
+				return
+			}
+			if i%3 == 0 {
+				b.ClearOverride()
+			}
+		}
+	}()
+	ts := &tickState{dir: "ne", target: "key", shooter: NewShooter(0)}
+	for i := 0; i < 200; i++ {
+		clock.Advance(100 * time.Millisecond)
+		b.tick(nopSender{}, ts)
+	}
+	<-done
+}
+
+func TestHostPort(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"localhost", "localhost:8080"},
+		{"127.0.0.1", "127.0.0.1:8080"},
+		{"::1", "[::1]:8080"},
+		{"[::1]", "[::1]:8080"},
+		{"fe80::1%eth0", "[fe80::1%eth0]:8080"},
+		{"[2001:db8::7]", "[2001:db8::7]:8080"},
+	}
+	for _, tt := range tests {
+		if got := hostPort(tt.host, 8080); got != tt.want {
+			t.Errorf("hostPort(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package gauntletbot
+
+import "time"
+
+// Clock is the source of time for everything that ages game state, so tests can swap in one they control.
+// Each bot has its own, from Config.Clock
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time // like time.After, on this clock
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,66 @@
+package gauntletbot
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a Clock that only moves when told to, for deterministic tests of anything that expires
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+// someone waiting on After for the clock to reach a time
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewFakeClock makes a clock stopped at start
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After fires once Advance has moved the clock on by d, or straight away if d isn't positive
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), c: ch})
+	return ch
+}
+
+// Advance moves the clock on by d, firing any After that's now due
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiting := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiting = append(waiting, w)
+		} else {
+			w.c <- c.now
+		}
+	}
+	c.waiters = waiting
+}
+
+// how many are waiting on After
+func (c *FakeClock) waiting() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	gauntletbot "github.com/neilo40/gauntletBot"
+)
+
+func main() {
+	cfg := gauntletbot.DefaultConfig()
+	flag.StringVar(&cfg.Host, "host", cfg.Host, "Host")
+	flag.IntVar(&cfg.Port, "port", cfg.Port, "Port")
+	flag.StringVar(&cfg.Network, "net", cfg.Network, "Network to reach the server over: udp, udp4 or udp6")
+	flag.StringVar(&cfg.Name, "name", cfg.Name, "Name")
+	flag.StringVar(&cfg.Class, "class", cfg.Class, "Class whose key we're after, if -name isn't one: warrior, valkyrie, elf, wizard or any added with -colors")
+	flag.Float64Var(&cfg.CautionThreshold, "caution", cfg.CautionThreshold, "Limit move length until this fraction (0-1) of the surrounding map is known, 0 to disable")
+	flag.IntVar(&cfg.CautionStep, "cautionstep", cfg.CautionStep, "Longest move (in game units) to make into unexplored territory")
+	flag.StringVar(&cfg.RoundStartMsg, "roundstart", cfg.RoundStartMsg, "Name of the server's round start message")
+	flag.StringVar(&cfg.RoundEndMsg, "roundend", cfg.RoundEndMsg, "Name of the server's round end message")
+	flag.BoolVar(&cfg.ResetMapOnRound, "resetmap", cfg.ResetMapOnRound, "Forget the map between rounds")
+	flag.BoolVar(&cfg.RejoinOnRound, "rejoin", cfg.RejoinOnRound, "Re-send requestjoin at the start of each round")
+	flag.IntVar(&cfg.WarmupTicks, "warmupticks", cfg.WarmupTicks, "Only explore for this many ticks at the start, 0 for no limit")
+	flag.Float64Var(&cfg.WarmupCoverage, "warmupcoverage", cfg.WarmupCoverage, "Only explore at the start until this fraction (0-1) of the surrounding map is known")
+	flag.Float64Var(&cfg.DiagonalCost, "diagonalcost", cfg.DiagonalCost, "Pathfinding cost of a diagonal step relative to a straight one, 0 to only move straight, -1 for what -metric makes it")
+	flag.StringVar(&cfg.DistanceMetric, "metric", cfg.DistanceMetric, "How to measure distances to targets: euclidean, manhattan or chebyshev")
+	flag.IntVar(&cfg.MaxMapCells, "maxmapcells", cfg.MaxMapCells, "Most wall and floor cells to remember, 0 for no limit")
+	flag.StringVar(&cfg.EvictPolicy, "evict", cfg.EvictPolicy, "Which cells to forget beyond -maxmapcells: farthest or oldest")
+	flag.IntVar(&cfg.TileSize, "tilesize", cfg.TileSize, "Map tile size to assume until it can be inferred from the walls and floors the server reports")
+	flag.IntVar(&cfg.MoveStep, "movestep", cfg.MoveStep, "Distance of each exploration move, for tiles of -tilesize")
+	flag.StringVar(&cfg.StartDir, "startdir", cfg.StartDir, "Initial exploration direction: ne, se, sw, nw or auto")
+	flag.StringVar(&cfg.ExploreBias, "explorebias", cfg.ExploreBias, "Exploration preference: none, center or unexplored")
+	flag.BoolVar(&cfg.Explore, "explore", cfg.Explore, "With nothing to go after, head for the nearest unexplored edge of the known floor instead of bouncing off walls")
+	flag.IntVar(&cfg.ShotDelay, "shotdelay", cfg.ShotDelay, "Ticks to wait between shots: 0 fires every tick, 2 every third")
+	flag.Float64Var(&cfg.FireCone, "firecone", cfg.FireCone, "Degrees either side of our facing an enemy must be within for us to fire")
+	flag.IntVar(&cfg.MaxShootRange, "shootrange", cfg.MaxShootRange, "Furthest away an enemy we'll fire at, 0 for no limit")
+	flag.IntVar(&cfg.MissThreshold, "missthreshold", cfg.MissThreshold, "Shots without a visible effect on the enemy before repositioning, 0 to disable")
+	flag.IntVar(&cfg.AlignWindow, "alignwindow", cfg.AlignWindow, "Number of recent shots to judge our aim over")
+	flag.Float64Var(&cfg.AlignThreshold, "alignthreshold", cfg.AlignThreshold, "Hold fire and reposition when fewer than this fraction of recent shots were lined up, 0 to disable")
+	colors := flag.String("colors", "", "Extra or replacement class:color pairs for key colours, comma separated")
+	team := flag.String("teammates", "", "Comma separated names of friendly players not to shoot")
+	flag.StringVar(&cfg.CombatProfile, "profile", cfg.CombatProfile, "Combat style: aggressive or kite")
+	flag.StringVar(&cfg.Strategy, "strategy", cfg.Strategy, "What to do each tick: default, or collector to gather items and run from enemies within -kitedist")
+	flag.IntVar(&cfg.KiteDistance, "kitedist", cfg.KiteDistance, "Distance the kite profile tries to keep from enemies")
+	flag.IntVar(&cfg.ExitGuard, "exitguard", cfg.ExitGuard, "With the key and the exit this close, shoot any enemy in the way before going through, 0 to just push on")
+	flag.BoolVar(&cfg.RushExit, "rushexit", cfg.RushExit, "Once the exit will let us out, ignore enemies and head for it")
+	flag.BoolVar(&cfg.ExitNeedsKey, "exitneedskey", cfg.ExitNeedsKey, "Assume the exit only works once we have the key; otherwise learn whether it does")
+	flag.DurationVar(&cfg.KeyTimeout, "keytimeout", cfg.KeyTimeout, "Give up looking for our key after this long, e.g. 3m.  0 never gives up")
+	flag.StringVar(&cfg.KeyGiveUp, "keygiveup", cfg.KeyGiveUp, "What to do after -keytimeout: exit, frag or keep")
+	flag.BoolVar(&cfg.Dodge, "dodge", cfg.Dodge, "Sidestep when an enemy is facing us, if the server reports facing")
+	flag.IntVar(&cfg.StrafeTicks, "strafeticks", cfg.StrafeTicks, "Ticks to sidestep the last enemy we saw for after taking damage, 0 to disable")
+	flag.IntVar(&cfg.PanicDistance, "panicdist", cfg.PanicDistance, "Distance within which an enemy is always engaged")
+	flag.DurationVar(&cfg.EnemyTTL, "enemyttl", cfg.EnemyTTL, "Forget an enemy not seen for this long, 0 to remember them forever")
+	flag.DurationVar(&cfg.WallTTL, "wallttl", cfg.WallTTL, "Forget a wall not seen for this long, in case it was destroyed or misread.  0 keeps walls forever")
+	flag.IntVar(&cfg.WallRadius, "wallradius", cfg.WallRadius, "Forget walls further away than this, 0 for no limit")
+	flag.DurationVar(&cfg.AmmoTTL, "ammottl", cfg.AmmoTTL, "Forget ammo not seen for this long, 0 to remember it forever")
+	flag.DurationVar(&cfg.FoodTTL, "foodttl", cfg.FoodTTL, "Forget food not seen for this long, 0 to remember it forever")
+	flag.IntVar(&cfg.EngageDistance, "engagedist", cfg.EngageDistance, "Furthest away an enemy we'll chase, 0 for no limit")
+	flag.IntVar(&cfg.AggressionHealth, "aggressionhealth", cfg.AggressionHealth, "Below this health, shrink -panicdist and -engagedist and back off from enemies beyond them.  0 disables")
+	flag.Float64Var(&cfg.AggressionCurve, "aggressioncurve", cfg.AggressionCurve, "Power of the health curve the distances shrink along, 1 for linear")
+	flag.Float64Var(&cfg.MinAggression, "minaggression", cfg.MinAggression, "Fraction of the distances we keep however hurt we are")
+	flag.IntVar(&cfg.FleeHealth, "fleehealth", cfg.FleeHealth, "Look for food when health drops below this")
+	flag.IntVar(&cfg.EvadeHealth, "evadehealth", cfg.EvadeHealth, "Below this health, run from enemies in sight unless there's food to grab, 0 to disable")
+	flag.IntVar(&cfg.ResumeHealth, "resumehealth", cfg.ResumeHealth, "Stop looking for food once health is back up to this")
+	flag.IntVar(&cfg.FleeAmmo, "fleeammo", cfg.FleeAmmo, "Look for ammo when we have fewer shots than this")
+	flag.IntVar(&cfg.ResumeAmmo, "resumeammo", cfg.ResumeAmmo, "Stop looking for ammo once we have this many shots")
+	flag.IntVar(&cfg.EnemyHistory, "enemyhistory", cfg.EnemyHistory, "Number of recent sightings of each enemy to estimate its velocity from")
+	flag.BoolVar(&cfg.PredictMotion, "predict", cfg.PredictMotion, "Compensate for network latency by predicting our own position")
+	flag.Int64Var(&cfg.Seed, "seed", cfg.Seed, "Seed for the random number generator")
+	flag.IntVar(&cfg.TickMs, "tickms", cfg.TickMs, "Milliseconds between moves, at least 10")
+	flag.IntVar(&cfg.JitterMs, "jitterms", cfg.JitterMs, "Random +/- variation in milliseconds to add to each tick, less than -tickms.  0 to disable")
+	flag.IntVar(&cfg.ClusterRadius, "clusterradius", cfg.ClusterRadius, "Head for the middle of groups of ammo or food this close together, 0 to disable")
+	flag.BoolVar(&cfg.RememberSpawns, "rememberspawns", cfg.RememberSpawns, "Remember where ammo and food has turned up and go back to look when there's none in sight")
+	flag.BoolVar(&cfg.PathDistance, "pathdistance", cfg.PathDistance, "Rank items by the walking distance round known walls rather than the straight line")
+	flag.BoolVar(&cfg.Weighted, "weighted", cfg.Weighted, "Choose between the key, exit, ammo, food and enemies by weighing them up rather than in a fixed order")
+	weights := flag.String("weights", "", "Target weights for -weighted as name:weight pairs, e.g. key:3,exit:4,ammo:1,food:1,enemy:1.5,distance:100")
+	logLevelName := flag.String("loglevel", "info", "Least severe messages to log: debug, info, warn or error")
+	flag.BoolVar(&cfg.Trace, "trace", cfg.Trace, "Log every decision with the reasoning behind it.  With -metricsaddr, POST /trace?on=0 or on=1 to switch it while running")
+	flag.BoolVar(&cfg.DryRun, "dryrun", cfg.DryRun, "Log commands instead of sending them to the server")
+	flag.IntVar(&cfg.MaxPPS, "maxpps", cfg.MaxPPS, "Most packets to send the server a second, dropping any more, 0 for no limit")
+	flag.BoolVar(&cfg.Resilient, "resilient", cfg.Resilient, "Log panics while handling a message or deciding a move and keep going")
+	script := flag.String("script", "", "Objectives to follow in order before playing normally, e.g. goto:100,200;ammo;exit")
+	flag.DurationVar(&cfg.ScriptTimeout, "scripttimeout", cfg.ScriptTimeout, "Move on from a scripted objective after this long, 0 to wait as long as it takes")
+	captureFile := flag.String("capture", "", "Write every datagram sent and received to this file")
+	recordFile := flag.String("record", "", "Append every datagram received to this file, to -replay later")
+	botList := flag.String("bots", "", "Run a team of bots sharing what they see of the map instead of one, as comma separated class:name pairs, e.g. warrior:alice,elf:bob")
+	replayFile := flag.String("replay", "", "Play a -record or -capture file back through the bot instead of connecting to a server")
+	flag.StringVar(&cfg.MetricsAddr, "metricsaddr", cfg.MetricsAddr, "Serve Prometheus metrics at /metrics, and the /override and /trace controls, on this address, e.g. :9100")
+	selfTestMode := flag.Bool("selftest", false, "Check we can join and move on the server, then exit")
+	flag.DurationVar(&cfg.ReconnectTimeout, "reconnect", cfg.ReconnectTimeout, "Reconnect and rejoin if the server goes quiet for this long, 0 to never")
+	flag.DurationVar(&cfg.MaxRuntime, "maxruntime", cfg.MaxRuntime, "Stop after this long, e.g. 2m.  0 runs forever")
+	flag.Parse()
+	if err := applyEnv(flag.CommandLine, "GAUNTLETBOT_"); err != nil {
+		log.Fatal(err)
+	}
+	if err := gauntletbot.SetLogLevel(*logLevelName); err != nil {
+		log.Fatal(err)
+	}
+	for _, mate := range strings.Split(*team, ",") {
+		if mate != "" {
+			cfg.Teammates[mate] = true
+		}
+	}
+	if err := cfg.ParseColors(*colors); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.ParseWeights(*weights); err != nil {
+		log.Fatal(err)
+	}
+	steps, err := gauntletbot.ParseScript(*script)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg.Script = steps
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	// with -bots, each bot of the team has its own class, checked as it's set up
+	if *botList == "" {
+		if color, ok := cfg.KeyColor(); ok {
+			gauntletbot.Infof("Looking for the %skey", color)
+		} else {
+			gauntletbot.Warnf("%s is not a class we know a key colour for, we won't recognise our key.  Set -class to say which is ours", cfg.Name)
+		}
+	}
+	if *captureFile != "" {
+		f, err := os.Create(*captureFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		cfg.Capture = f
+	}
+	if *recordFile != "" {
+		f, err := os.OpenFile(*recordFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		cfg.Record = f
+	}
+	if *replayFile != "" {
+		// there's no server to fall silent or to send commands to
+		cfg.ReconnectTimeout = 0
+		cfg.DryRun = true
+	}
+
+	specs, err := gauntletbot.ParseBots(*botList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(specs) > 0 {
+		if *selfTestMode || *replayFile != "" {
+			log.Fatal("-bots can't be combined with -selftest or -replay")
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if !runTeam(ctx, cfg, specs) {
+			gauntletbot.Infof("Shutting down without completing our objectives")
+			os.Exit(2)
+		}
+		gauntletbot.Infof("Shutting down, objectives complete")
+		return
+	}
+
+	bot := gauntletbot.NewBot(cfg)
+	if *selfTestMode {
+		if err := bot.SelfTest(); err != nil {
+			gauntletbot.Errorf("Self test FAILED: %v", err)
+			os.Exit(1)
+		}
+		gauntletbot.Infof("Self test passed")
+		return
+	}
+
+	if *replayFile != "" {
+		f, err := os.Open(*replayFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		if err := bot.Replay(f); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go gauntletbot.DumpOnSignal(ctx, bot)
+	if err := bot.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+	if !bot.ObjectivesMet() {
+		gauntletbot.Infof("Shutting down without completing our objectives")
+		os.Exit(2)
+	}
+	gauntletbot.Infof("Shutting down, objectives complete")
+}
+
+// fill in any flag not given on the command line from the environment, e.g. -host from GAUNTLETBOT_HOST.
+// explicit flags win over the environment, which wins over the defaults
+func applyEnv(flags *flag.FlagSet, prefix string) error {
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if explicit[f.Name] || err != nil {
+			return
+		}
+		envName := prefix + strings.ToUpper(f.Name)
+		if value, ok := os.LookupEnv(envName); ok {
+			if setErr := flags.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("bad value for %s: %v", envName, setErr)
+			}
+		}
+	})
+	return err
+}
+
+// run a bot for each spec, as teammates sharing an arena, until they've all stopped.
+// Reports whether every one of them completed its objectives
+func runTeam(ctx context.Context, cfg gauntletbot.Config, specs []gauntletbot.BotSpec) bool {
+	arena := gauntletbot.NewArena()
+	bots := make([]*gauntletbot.Bot, len(specs))
+	for i, spec := range specs {
+		botCfg := cfg
+		botCfg.Name = spec.Name
+		botCfg.Class = spec.Class
+		botCfg.Seed = cfg.Seed + int64(i) // so they don't all make the same moves
+		botCfg.Teammates = make(map[string]bool)
+		for mate := range cfg.Teammates {
+			botCfg.Teammates[mate] = true
+		}
+		for _, other := range specs {
+			if other.Name != spec.Name {
+				botCfg.Teammates[other.Name] = true
+			}
+		}
+		if i > 0 {
+			// only one of us can listen on the metrics address
+			botCfg.MetricsAddr = ""
+		}
+		if err := botCfg.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		bots[i] = gauntletbot.NewBot(botCfg)
+		arena.Join(bots[i])
+	}
+	arenaCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go arena.Run(arenaCtx)
+	go gauntletbot.DumpOnSignal(arenaCtx, bots...)
+	var wg sync.WaitGroup
+	for i, bot := range bots {
+		wg.Add(1)
+		go func(name string, bot *gauntletbot.Bot) {
+			defer wg.Done()
+			if err := bot.Run(ctx); err != nil {
+				gauntletbot.Errorf("%s: %v", name, err)
+			}
+		}(specs[i].Name, bot)
+	}
+	wg.Wait()
+	for _, bot := range bots {
+		if !bot.ObjectivesMet() {
+			return false
+		}
+	}
+	return true
+}
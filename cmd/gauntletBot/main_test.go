@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		host    string
+		port    int
+		wantErr bool
+	}{
+		{"defaults", nil, nil, "localhost", 8080, false},
+		{"from the environment", nil, map[string]string{"TEST_HOST": "example.com", "TEST_PORT": "9000"}, "example.com", 9000, false},
+		{"flags win", []string{"-host", "flag.example"}, map[string]string{"TEST_HOST": "example.com", "TEST_PORT": "9000"}, "flag.example", 9000, false},
+		{"other prefixes ignored", nil, map[string]string{"OTHER_HOST": "example.com"}, "localhost", 8080, false},
+		{"bad value", nil, map[string]string{"TEST_PORT": "lots"}, "", 0, true},
+		{"bad value under a flag", []string{"-port", "9000"}, map[string]string{"TEST_PORT": "lots"}, "localhost", 9000, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.SetOutput(io.Discard)
+			host := flags.String("host", "localhost", "")
+			port := flags.Int("port", 8080, "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := applyEnv(flags, "TEST_")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyEnv() = %v, want an error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (*host != tt.host || *port != tt.port) {
+				t.Errorf("host %s and port %d, want %s and %d", *host, *port, tt.host, tt.port)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package gauntletbot
+
+import (
+	"math"
+	"time"
+)
+
+// Shooter paces our shots, allowing one every Delay+1 ticks
+type Shooter struct {
+	Delay int // ticks to wait between shots
+	count int
+}
+
+// NewShooter makes a Shooter that waits Delay ticks before its first shot
+func NewShooter(delay int) *Shooter {
+	return &Shooter{Delay: delay, count: delay}
+}
+
+// ShouldFire is called once a tick, and says whether this is a tick to shoot on
+func (s *Shooter) ShouldFire() bool {
+	if s.count == 0 {
+		s.count = s.Delay
+		return true
+	}
+	s.count--
+	return false
+}
+
+// Reset starts the wait for the next shot over again
+func (s *Shooter) Reset() {
+	s.count = s.Delay
+}
+
+// if there's an enemy in sight, shoot in its general direction
+func (b *Bot) shoot() {
+	here := b.selfLoc()
+	// one look at the enemy, so who we aim at is who we count the shot against
+	e := b.snapshotEnemy()
+	if enemy, ok := b.sighted(here, e); ok {
+		// aim where they're going, not where they were
+		aim := b.leadTarget(e.Name, enemy)
+		dir := directionTo(here, aim)
+		b.face(dir)
+		if b.cfg.MaxShootRange > 0 && distanceBetween(here, enemy) > float64(b.cfg.MaxShootRange) {
+			// too far to be worth the ammo, they'll likely have moved by the time it gets there
+			return
+		}
+		if angleOff(here, aim, dir) > b.cfg.FireCone {
+			// we can only face eight ways, and they're in a gap between them
+			return
+		}
+		if b.teammateInLine(here, aim) {
+			b.tracef("holding fire, teammate in the way")
+			return
+		}
+		b.recordAlignment(plausibleHit(here, aim, dir, b.tileSize()))
+		if b.alignmentPoor() {
+			// too many of our recent shots couldn't have hit, find a better line before spending more ammo
+			if b.repositioning == 0 {
+				Infof("Shots badly aligned, holding fire and repositioning")
+				b.repositioning = b.cfg.RepositionTicks
+				b.repositionDir = rotate(dir, 2)
+			}
+			return
+		}
+		if b.lastShotTarget != nil && *b.lastShotTarget == enemy {
+			b.shotsWithoutEffect++
+		} else {
+			b.shotsWithoutEffect = 0
+		}
+		if b.cfg.MissThreshold > 0 && b.shotsWithoutEffect >= b.cfg.MissThreshold {
+			// they're well covered from here, stop wasting ammo and try a different angle
+			Infof("%d shots without effect, repositioning", b.shotsWithoutEffect)
+			b.repositioning = b.cfg.RepositionTicks
+			b.repositionDir = rotate(dir, 2)
+			b.shotsWithoutEffect = 0
+			b.lastShotTarget = nil
+			return
+		}
+		b.lastShotTarget = &enemy
+		b.fire()
+		b.metrics.noteShot(e.Name)
+	}
+}
+
+// how keen we are to fight at a given health: 1 at AggressionHealth and above, falling along
+// a curve of power AggressionCurve to MinAggression at no health
+func (b *Bot) aggressionFactor(health int) float64 {
+	if b.cfg.AggressionHealth <= 0 {
+		return 1
+	}
+	f := math.Max(0, math.Min(float64(health)/float64(b.cfg.AggressionHealth), 1))
+	return math.Max(b.cfg.MinAggression, math.Pow(f, b.cfg.AggressionCurve))
+}
+
+// the furthest enemy we'll go after at a given health.  Beyond it we explore, or when
+// we're not at full strength, back off.  With no EngageDistance we'll chase anyone
+func (b *Bot) engageDistance(health int) int {
+	if b.cfg.EngageDistance == 0 {
+		return math.MaxInt
+	}
+	return int(float64(b.cfg.EngageDistance) * b.aggressionFactor(health))
+}
+
+// enemies within this distance get fought whatever else we're doing, shrinking as we get hurt
+func (b *Bot) panicDistance(health int) int {
+	return int(float64(b.cfg.PanicDistance) * b.aggressionFactor(health))
+}
+
+// where the enemy is, if we've seen one in the last second with nothing we know of between us
+func (b *Bot) enemyInSight(here Loc) (Loc, bool) {
+	return b.sighted(here, b.snapshotEnemy())
+}
+
+// where a sighting of an enemy puts them, if it's from the last second with nothing we know of between us
+func (b *Bot) sighted(here Loc, e enemySighting) (Loc, bool) {
+	if e.Loc == nil || !e.Seen.After(b.clock.Now().Add(-1*time.Second)) {
+		return Loc{}, false
+	}
+	return *e.Loc, b.canSeeItem(here, *e.Loc)
+}
+
+// with the key and the exit within ExitGuard, is there an enemy we can see in the way: near the
+// straight line to the exit or blocking the only route we know there?  Then it's worth standing our ground
+// and shooting them rather than walking into their fire
+func (b *Bot) defendingExit(p Player) bool {
+	if b.cfg.ExitGuard == 0 || !p.HasKey || p.Exit == nil {
+		return false
+	}
+	here := b.selfLoc()
+	exit := *p.Exit
+	if distanceBetween(here, exit) > float64(b.cfg.ExitGuard) {
+		return false
+	}
+	foe, ok := b.enemyInSight(here)
+	if !ok {
+		return false
+	}
+	return distanceToSegment(foe, here, exit) <= float64(b.tileSize()) || b.enemyBlocksPath(here, exit, foe)
+}
+
+// OnDamage subscribes f to being told whenever our health drops, with what it was and what it is now.
+// Subscribe before Run; f is called from the read loop, so must be quick and do its own locking
+func (b *Bot) OnDamage(f func(prev int, curr int)) {
+	b.damageHooks = append(b.damageHooks, f)
+}
+
+// we've just lost health: strafe for a few ticks, if we're configured to, and tell the subscribers
+func (b *Bot) onDamage(prev int, curr int) {
+	debugf("Took %d damage, health now %d", prev-curr, curr)
+	if b.cfg.StrafeTicks > 0 {
+		b.strafing.Store(int32(b.cfg.StrafeTicks))
+	}
+	for _, f := range b.damageHooks {
+		f(prev, curr)
+	}
+}
+
+// which way to strafe after taking damage: sideways to the enemy we last saw, on whichever side we
+// picked when we started.  False if we've no idea where the shots came from
+func (b *Bot) strafeDirection(here Loc) (string, bool) {
+	e := b.snapshotEnemy()
+	if e.Loc == nil {
+		return "", false
+	}
+	if b.strafeSide == 0 {
+		b.strafeSide = 2
+		if b.rng.Intn(2) == 0 {
+			b.strafeSide = -2
+		}
+	}
+	return rotate(directionTo(here, *e.Loc), b.strafeSide), true
+}
+
+// if the enemy is facing us closely enough to hit, which way should we step to get out of their line of fire?
+func (b *Bot) dodgeDirection(here Loc) (string, bool) {
+	if !b.cfg.Dodge {
+		return "", false
+	}
+	facing := b.snapshotEnemy().Dir
+	enemy, ok := b.enemyInSight(here)
+	if !ok || facing == "" || angleOff(enemy, here, facing) > b.cfg.FireCone {
+		return "", false
+	}
+	return rotate(facing, 2), true
+}
+
+// would a shot fired in dir from one location pass through a target tile at the other?
+func plausibleHit(from Loc, to Loc, dir string, tile int) bool {
+	rad := float64(compassIndex(dir)) * 45 * math.Pi / 180
+	// unit vector for the facing, remembering y grows southwards
+	ux := math.Sin(rad)
+	uy := -math.Cos(rad)
+	dx := float64(to.X - from.X)
+	dy := float64(to.Y - from.Y)
+	along := dx*ux + dy*uy
+	across := math.Abs(dx*uy - dy*ux)
+	return along > 0 && across <= float64(tile)/2
+}
+
+// remember whether the latest shot was lined up, keeping only the most recent window of shots
+func (b *Bot) recordAlignment(aligned bool) {
+	if b.cfg.AlignWindow <= 0 {
+		return
+	}
+	b.alignment = append(b.alignment, aligned)
+	if len(b.alignment) > b.cfg.AlignWindow {
+		b.alignment = b.alignment[len(b.alignment)-b.cfg.AlignWindow:]
+	}
+}
+
+// over a full window of recent shots, was too small a fraction of them lined up?
+func (b *Bot) alignmentPoor() bool {
+	if b.cfg.AlignThreshold <= 0 || len(b.alignment) < b.cfg.AlignWindow {
+		return false
+	}
+	aligned := 0
+	for _, a := range b.alignment {
+		if a {
+			aligned++
+		}
+	}
+	return float64(aligned)/float64(len(b.alignment)) < b.cfg.AlignThreshold
+}
+
+// which of the eight compass directions points most directly from one location to another?
+func directionTo(from Loc, to Loc) string {
+	steps := int(math.Round(bearing(from, to) / 45))
+	return compass[steps%len(compass)]
+}
+
+// compass bearing in degrees (0-360, clockwise from north) from one location to another.  y grows southwards
+func bearing(from Loc, to Loc) float64 {
+	degrees := math.Atan2(float64(to.X-from.X), float64(from.Y-to.Y)) * 180 / math.Pi
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+// how far off (in degrees, 0-180) is the target from the direction we're facing?
+func angleOff(from Loc, to Loc, dir string) float64 {
+	facing := float64(compassIndex(dir)) * 45
+	diff := math.Abs(bearing(from, to) - facing)
+	if diff > 180 {
+		diff = 360 - diff
+	}
+	return diff
+}
+
+// the compass direction that takes us most directly away from an enemy
+func fleeDirection(self Loc, enemy Loc) string {
+	return rotate(directionTo(self, enemy), 4)
+}
+
+// turn clockwise by the given number of 45 degree steps (negative turns anticlockwise)
+func rotate(dir string, steps int) string {
+	i := compassIndex(dir)
+	if i < 0 {
+		return dir
+	}
+	return compass[((i+steps)%len(compass)+len(compass))%len(compass)]
+}
+
+// position of a direction in the compass, clockwise from north, or -1 if it isn't one
+func compassIndex(dir string) int {
+	for i, d := range compass {
+		if d == dir {
+			return i
+		}
+	}
+	return -1
+}
+
+// is a recently seen teammate standing on (or close to) the line between us and the target?
+func (b *Bot) teammateInLine(from Loc, to Loc) bool {
+	deadline := b.clock.Now().Add(-1 * time.Second)
+	b.teamMutex.Lock()
+	defer b.teamMutex.Unlock()
+	for _, mate := range b.state.Teammates {
+		if mate.Seen.After(deadline) && distanceToSegment(mate.Loc, from, to) <= float64(b.tileSize()) {
+			return true
+		}
+	}
+	return false
+}
+
+// shortest distance from a point to the line segment between a and b
+func distanceToSegment(p Loc, a Loc, b Loc) float64 {
+	dx := float64(b.X - a.X)
+	dy := float64(b.Y - a.Y)
+	t := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = (float64(p.X-a.X)*dx + float64(p.Y-a.Y)*dy) / lengthSq
+		t = math.Max(0, math.Min(1, t))
+	}
+	return math.Hypot(float64(p.X)-(float64(a.X)+t*dx), float64(p.Y)-(float64(a.Y)+t*dy))
+}
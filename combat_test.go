@@ -0,0 +1,348 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestShooterCadence(t *testing.T) {
+	tests := []struct {
+		delay int
+		reset int // the tick to Reset on, or -1 not to
+		want  string
+	}{
+		{delay: 0, reset: -1, want: "xxxxxxxxxx"},
+		{delay: 1, reset: -1, want: ".x.x.x.x.x"},
+		{delay: 3, reset: -1, want: "...x...x.."},
+		// a Reset waits the whole delay again, counting the tick it happens on
+		{delay: 3, reset: 5, want: "...x....x."},
+		{delay: 2, reset: 4, want: "..x...x..x"},
+		{delay: 2, reset: 2, want: "....x..x.."},
+	}
+	for _, tt := range tests {
+		s := NewShooter(tt.delay)
+		got := ""
+		for tick := 0; tick < len(tt.want); tick++ {
+			if tick == tt.reset {
+				s.Reset()
+			}
+			if s.ShouldFire() {
+				got += "x"
+			} else {
+				got += "."
+			}
+		}
+		if got != tt.want {
+			t.Errorf("delay %d reset at %d: fired %s, want %s", tt.delay, tt.reset, got, tt.want)
+		}
+	}
+}
+
+func TestTeammateInLine(t *testing.T) {
+	from, to := Loc{X: 100, Y: 100}, Loc{X: 200, Y: 100}
+	tests := []struct {
+		name string
+		mate Loc
+		age  time.Duration
+		want bool
+	}{
+		{"on the line", Loc{X: 150, Y: 100}, 0, true},
+		{"a tile off it", Loc{X: 150, Y: 108}, 0, true},
+		{"well off it", Loc{X: 150, Y: 120}, 0, false},
+		{"behind the target", Loc{X: 220, Y: 100}, 0, false},
+		{"behind us", Loc{X: 80, Y: 100}, 0, false},
+		{"seen too long ago", Loc{X: 150, Y: 100}, 2 * time.Second, false},
+	}
+	for _, tt := range tests {
+		b, clock := newTestBot(func(cfg *Config) { cfg.Teammates["elf"] = true })
+		b.handleMessage(fmt.Sprintf("nearbyplayer:elf,elf,%d,%d", tt.mate.X, tt.mate.Y))
+		clock.Advance(tt.age)
+		if got := b.teammateInLine(from, to); got != tt.want {
+			t.Errorf("%s: teammate in line %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// what shoot plans from 100,100 at an enemy at the given spot
+func planShot(b *Bot, enemy Loc) []string {
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	b.handleMessage(fmt.Sprintf("nearbyplayer:orc,grunt,%d,%d", enemy.X, enemy.Y))
+	b.planning = true
+	b.plan = nil
+	b.shoot()
+	b.planning = false
+	return b.plan
+}
+
+// a spot the given distance from 100,100 on a compass bearing, in degrees clockwise from north
+func onBearing(degrees float64, distance float64) Loc {
+	rad := degrees * math.Pi / 180
+	return Loc{X: 100 + int(math.Round(distance*math.Sin(rad))), Y: 100 - int(math.Round(distance*math.Cos(rad)))}
+}
+
+func TestFireCone(t *testing.T) {
+	tests := []struct {
+		bearing float64
+		fire    bool
+	}{
+		{0, true},
+		{8, true},
+		{12, false},
+		{-8, true},
+		{-12, false},
+		// just inside and outside the cone around east
+		{78, false},
+		{82, true},
+		{98, true},
+		{102, false},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBot(func(cfg *Config) { cfg.FireCone = 10 })
+		enemy := onBearing(tt.bearing, 200)
+		plan := planShot(b, enemy)
+		if fired := slices.Contains(plan, "fire:"); fired != tt.fire {
+			t.Errorf("enemy at %v, %.2f degrees off our facing: fired %v, want %v (planned %q)",
+				enemy, angleOff(Loc{X: 100, Y: 100}, enemy, directionTo(Loc{X: 100, Y: 100}, enemy)), fired, tt.fire, plan)
+		}
+	}
+}
+
+func TestMaxShootRange(t *testing.T) {
+	tests := []struct {
+		distance int
+		fire     bool
+	}{
+		{50, true},
+		{99, true},
+		{100, true},
+		{101, false},
+		{300, false},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBot(func(cfg *Config) { cfg.MaxShootRange = 100 })
+		plan := planShot(b, Loc{X: 100 + tt.distance, Y: 100})
+		if fired := slices.Contains(plan, "fire:"); fired != tt.fire {
+			t.Errorf("enemy %d away: fired %v, want %v (planned %q)", tt.distance, fired, tt.fire, plan)
+		}
+		// out of range or not, we keep facing them
+		if !slices.Contains(plan, "facedirection:e") {
+			t.Errorf("enemy %d away: didn't face them, planned %q", tt.distance, plan)
+		}
+	}
+}
+
+func TestDodgeDirection(t *testing.T) {
+	tests := []struct {
+		name  string
+		dodge bool
+		enemy string
+		want  string // "" for no dodge
+	}{
+		{"facing us", true, "nearbyplayer:orc,grunt,140,100,w", "n"},
+		{"facing us from the north", true, "nearbyplayer:orc,grunt,100,60,s", "w"},
+		{"facing us on a diagonal", true, "nearbyplayer:orc,grunt,140,60,sw", "nw"},
+		{"facing away", true, "nearbyplayer:orc,grunt,140,100,e", ""},
+		{"facing across us", true, "nearbyplayer:orc,grunt,140,100,n", ""},
+		{"facing unknown", true, "nearbyplayer:orc,grunt,140,100", ""},
+		{"dodging off", false, "nearbyplayer:orc,grunt,140,100,w", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := newTestBot(func(cfg *Config) { cfg.Dodge = tt.dodge })
+			b.handleMessage("playerupdate:100,100,10,10,False")
+			b.handleMessage(tt.enemy)
+			dir, ok := b.dodgeDirection(b.selfLoc())
+			if ok != (tt.want != "") || dir != tt.want {
+				t.Errorf("dodgeDirection() = %q, %v, want %q", dir, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggressionFactor(t *testing.T) {
+	tests := []struct {
+		name              string
+		threshold         int
+		curve, floor      float64
+		health            int
+		want              float64
+		engageAt, panicAt int
+	}{
+		{"healthy", 10, 1, 0, 10, 1, 200, 50},
+		{"above the threshold", 10, 1, 0, 15, 1, 200, 50},
+		{"half health, linear", 10, 1, 0, 5, 0.5, 100, 25},
+		{"half health, squared", 10, 2, 0, 5, 0.25, 50, 12},
+		{"half health, square root", 10, 0.5, 0, 5, math.Sqrt(0.5), 141, 35},
+		{"dying, held at the floor", 10, 2, 0.3, 1, 0.3, 60, 15},
+		{"no health", 10, 1, 0, 0, 0, 0, 0},
+		{"negative health", 10, 1, 0.1, -3, 0.1, 20, 5},
+		{"threshold off", 0, 2, 0, 1, 1, 200, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := newTestBot(func(cfg *Config) {
+				cfg.AggressionHealth = tt.threshold
+				cfg.AggressionCurve = tt.curve
+				cfg.MinAggression = tt.floor
+				cfg.EngageDistance = 200
+				cfg.PanicDistance = 50
+			})
+			if got := b.aggressionFactor(tt.health); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("aggressionFactor(%d) = %g, want %g", tt.health, got, tt.want)
+			}
+			if got := b.engageDistance(tt.health); got != tt.engageAt {
+				t.Errorf("engageDistance(%d) = %d, want %d", tt.health, got, tt.engageAt)
+			}
+			if got := b.panicDistance(tt.health); got != tt.panicAt {
+				t.Errorf("panicDistance(%d) = %d, want %d", tt.health, got, tt.panicAt)
+			}
+		})
+	}
+}
+
+func TestDamageStartsStrafing(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.StrafeTicks = 3 })
+	var hits [][2]int
+	b.OnDamage(func(prev int, curr int) { hits = append(hits, [2]int{prev, curr}) })
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	b.handleMessage("playerupdate:100,100,12,10,False") // healing isn't damage
+	b.handleMessage("nearbyplayer:orc,grunt,180,100")
+	b.handleMessage("playerupdate:100,100,9,10,False")
+	if want := [][2]int{{12, 9}}; !reflect.DeepEqual(hits, want) {
+		t.Fatalf("damage hooks heard %v, want %v", hits, want)
+	}
+
+	ts := &tickState{dir: "ne", target: "enemy", shooter: NewShooter(0)}
+	var side int
+	for i := 1; i <= 3; i++ {
+		b.tick(nopSender{}, ts)
+		if ts.target != "strafe" {
+			t.Fatalf("tick %d went for %s, want strafe", i, ts.target)
+		}
+		// sideways to an enemy due east is due north or south, and the same way every tick
+		step := b.moveGoal.Y - 100
+		if b.moveGoal.X != 100 || step == 0 || (side != 0 && step != side) {
+			t.Fatalf("tick %d stepped to %v, want the same side of (100,100) as before, due north or south", i, *b.moveGoal)
+		}
+		side = step
+	}
+	b.tick(nopSender{}, ts)
+	if ts.target == "strafe" || b.strafeSide != 0 {
+		t.Errorf("still strafing (%s, side %d) after StrafeTicks ran out", ts.target, b.strafeSide)
+	}
+}
+
+func TestDamageWithoutStrafing(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.StrafeTicks = 0 })
+	heard := 0
+	b.OnDamage(func(int, int) { heard++ })
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	b.handleMessage("playerupdate:100,100,9,10,False")
+	if heard != 1 || b.strafing.Load() != 0 {
+		t.Errorf("hooks heard %d hits and %d ticks of strafing set, want 1 and none", heard, b.strafing.Load())
+	}
+}
+
+func TestFleeDirection(t *testing.T) {
+	self := Loc{X: 100, Y: 100}
+	tests := []struct {
+		bearing float64 // of the enemy from us
+		want    string
+	}{
+		{0, "s"},
+		{45, "sw"},
+		{90, "w"},
+		{135, "nw"},
+		{180, "n"},
+		{225, "ne"},
+		{270, "e"},
+		{315, "se"},
+		// rounded to the nearest compass point
+		{20, "s"},
+		{25, "sw"},
+		{350, "s"},
+		{200, "n"},
+	}
+	for _, tt := range tests {
+		enemy := onBearing(tt.bearing, 100)
+		got := fleeDirection(self, enemy)
+		if got != tt.want {
+			t.Errorf("enemy at %v, bearing %g: fleeDirection() = %s, want %s", enemy, tt.bearing, got, tt.want)
+			continue
+		}
+		if away := projectDir(self, got, 20); distanceBetween(away, enemy) <= distanceBetween(self, enemy) {
+			t.Errorf("enemy at %v: fleeing %s to %v doesn't get us further away", enemy, got, away)
+		}
+	}
+}
+
+func TestAlignThreshold(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) {
+		cfg.AlignWindow = 4
+		cfg.AlignThreshold = 0.5
+		cfg.MissThreshold = 0
+	})
+	// inside the fire cone, but too far off the line we can shoot along to hit
+	wide := onBearing(30, 200)
+	east := onBearing(90, 200)
+	shots := []struct {
+		enemy Loc
+		fire  bool
+	}{
+		{wide, true}, {wide, true}, {wide, true}, // not enough shots yet to judge
+		{wide, false}, // none of the last 4 lined up
+		{east, false}, // 1 of 4
+		{east, true},  // 2 of 4 is enough
+		{wide, true},
+	}
+	for i, shot := range shots {
+		plan := planShot(b, shot.enemy)
+		if fired := slices.Contains(plan, "fire:"); fired != shot.fire {
+			t.Fatalf("shot %d at %v: fired %v, want %v (alignment %v)", i+1, shot.enemy, fired, shot.fire, b.alignment)
+		}
+	}
+	if b.repositioning != b.cfg.RepositionTicks {
+		t.Errorf("%d ticks of repositioning after holding fire, want %d", b.repositioning, b.cfg.RepositionTicks)
+	}
+}
+
+func TestNoShotCountdownWithoutEnemy(t *testing.T) {
+	b, clock := newTestBot(nil)
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	ts := &tickState{dir: "ne", target: "key", shooter: NewShooter(2)}
+	fired := func() bool {
+		return slices.Contains(b.tick(nopSender{}, ts), "fire:")
+	}
+	// a long quiet spell mustn't leave a shot ready to go
+	for i := 0; i < 10; i++ {
+		if fired() {
+			t.Fatal("fired with nobody about")
+		}
+	}
+	var got []bool
+	for i := 0; i < 3; i++ {
+		b.handleMessage("nearbyplayer:orc,grunt,200,100")
+		got = append(got, fired())
+	}
+	if want := []bool{false, false, true}; !slices.Equal(got, want) {
+		t.Errorf("fired %v on the ticks after an enemy appeared, want %v", got, want)
+	}
+
+	// losing sight of them part way through the wait starts it over
+	b.handleMessage("nearbyplayer:orc,grunt,200,100")
+	fired()
+	clock.Advance(2 * time.Second)
+	fired()
+	got = got[:0]
+	for i := 0; i < 3; i++ {
+		b.handleMessage("nearbyplayer:orc,grunt,200,100")
+		got = append(got, fired())
+	}
+	if want := []bool{false, false, true}; !slices.Equal(got, want) {
+		t.Errorf("fired %v on the ticks after they came back, want %v", got, want)
+	}
+}
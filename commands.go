@@ -0,0 +1,107 @@
+package gauntletbot
+
+import (
+	"fmt"
+)
+
+// send a command: into the plan if the write loop is in the middle of a tick, otherwise straight to the server
+func (b *Bot) send(msg string) {
+	if b.planning {
+		b.plan = append(b.plan, msg)
+		return
+	}
+	b.transmit(b.sender, msg)
+}
+
+func (b *Bot) transmit(out Sender, msg string) {
+	b.tracef("send %s", msg)
+	if err := out.Send([]byte(msg)); err != nil {
+		b.tracef("send failed: %v", err)
+	}
+}
+
+// format the messages as needed and send to the server
+func (b *Bot) join(name string) {
+	joinString := "requestjoin:" + name
+	// we may be rejoining from the read loop or the watchdog, mid tick, so this can't wait for the plan.
+	// Nor can it be dropped by the rate limiter: without it we're not in the game
+	b.transmit(b.direct, joinString)
+}
+
+func (b *Bot) face(dir string) {
+	msgString := "facedirection:" + dir
+	b.send(msgString)
+}
+
+func (b *Bot) moveTo(to Loc) {
+	from := b.snapshotPlayer().Loc
+	to = b.cautiousStep(from, to)
+	b.noteMoveSent(from)
+	b.moveGoal = &to
+	msgString := fmt.Sprintf("moveto:%d,%d", to.X, to.Y)
+	b.send(msgString)
+}
+
+// stop where we are, rather than carrying on to wherever the last moveto was taking us.  Not a real
+// move, so it's kept out of the latency measurements and isn't cut short by cautiousStep
+func (b *Bot) holdStill(here Loc) {
+	b.moveGoal = &here
+	msgString := fmt.Sprintf("moveto:%d,%d", here.X, here.Y)
+	b.send(msgString)
+}
+
+// move in a direction, but use the server's moveto command.  If a wall we know of is in the way,
+// veer off by 45 degrees and then 90, trying clockwise first, rather than grinding against it
+func (b *Bot) moveToDir(dir string) {
+	here := b.snapshotPlayer().Loc
+	step := b.moveStep()
+	to := projectDir(here, dir, step)
+	for _, turn := range []int{0, 1, -1, 2, -2} {
+		if clear := projectDir(here, rotate(dir, turn), step); b.canSeeItem(here, clear) {
+			to = clear
+			break
+		}
+	}
+	b.moveGoal = &to
+	msgString := fmt.Sprintf("moveto:%d,%d", to.X, to.Y)
+	b.send(msgString)
+}
+
+// where a step of the given length in a compass direction takes us.  Diagonal steps go that far on both axes
+func projectDir(from Loc, dir string, step int) Loc {
+	x := from.X
+	y := from.Y
+	switch dir {
+	case "n":
+		y -= step
+	case "e":
+		x += step
+	case "s":
+		y += step
+	case "w":
+		x -= step
+	case "ne":
+		y -= step
+		x += step
+	case "se":
+		y += step
+		x += step
+	case "sw":
+		y += step
+		x -= step
+	case "nw":
+		y -= step
+		x -= step
+	}
+	return Loc{X: x, Y: y}
+}
+
+func (b *Bot) moveDir(dir string) {
+	msgString := fmt.Sprintf("movedirection:%s", dir)
+	b.send(msgString)
+}
+
+func (b *Bot) fire() {
+	msgString := "fire:"
+	b.send(msgString)
+}
@@ -0,0 +1,285 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is everything about a bot that can be tuned without changing code
+type Config struct {
+	Host    string
+	Port    int
+	Network string            // udp, or udp4 or udp6 to only use one address family
+	Name    string            // the name we ask to join as
+	Class   string            // the class whose key is ours, if Name isn't one.  Empty to go by Name
+	Colors  map[string]string // player class -> the colour of that class's key
+
+	ShotDelay        int     // ticks to wait between shots, so we fire every ShotDelay+1 ticks while an enemy is in sight
+	FireCone         float64 // only fire when the enemy is within this many degrees of the way we're facing
+	MaxShootRange    int     // only fire at enemies at most this far away, though we still face them.  0 for no limit
+	MissThreshold    int     // shots in a row with no visible effect on the enemy before we move somewhere else.  0 disables
+	RepositionTicks  int     // how long to spend repositioning once we've given up on a firing spot
+	AlignWindow      int     // how many recent shots to judge our aim over
+	AlignThreshold   float64 // hold fire when fewer than this fraction of the window's shots were lined up.  0 disables
+	CombatProfile    string  // aggressive chases enemies down, kite keeps them at KiteDistance while firing
+	Strategy         string  // default for our own judgement, or collector to gather items and run from enemies
+	KiteDistance     int
+	ExitGuard        int             // with the key and the exit this close, stop and shoot any enemy in the way before going through.  0 disables
+	RushExit         bool            // once we can use the exit, head straight for it rather than fighting
+	ExitNeedsKey     bool            // assume the exit is locked until we hold our key.  If false we find out by trying it
+	KeyTimeout       time.Duration   // how long to look for our key before doing KeyGiveUp instead.  0 never gives up
+	KeyGiveUp        string          // what to do once we've given up on the key: exit (if it'll let us out), frag or keep
+	Dodge            bool            // step sideways out of an enemy's line of fire, if the server says which way they face
+	StrafeTicks      int             // how many ticks to sidestep for after taking damage.  0 disables
+	PanicDistance    int             // enemies closer than this get fought regardless, to defend ourselves
+	EnemyTTL         time.Duration   // forget an enemy we haven't seen for this long.  0 remembers them forever
+	WallTTL          time.Duration   // forget a wall we haven't been told about for this long, in case it's gone.  0 keeps walls forever
+	WallRadius       int             // forget walls further away than this, to be relearnt if we come back.  0 keeps them wherever they are
+	AmmoTTL          time.Duration   // forget ammo we haven't seen for this long, it's probably been picked up.  0 remembers it forever
+	FoodTTL          time.Duration   // the same for food
+	EngageDistance   int             // the furthest enemy we'll go after.  0 for no limit
+	AggressionHealth int             // health below which we shrink PanicDistance and EngageDistance.  0 disables
+	AggressionCurve  float64         // how quickly they shrink: 1 in proportion to health, higher to back off sooner
+	MinAggression    float64         // the smallest fraction of them we'll shrink to
+	Teammates        map[string]bool // names of players on our side, who we must never shoot
+	FleeHealth       int             // go looking for food when health drops below this
+	EvadeHealth      int             // below this, run directly away from an enemy in sight unless there's food to grab.  0 disables
+	ResumeHealth     int             // and go back to fighting once it's at least this
+	FleeAmmo         int             // go looking for ammo when we have fewer shots than this
+	ResumeAmmo       int             // and go back to fighting once we have at least this many
+
+	CautionThreshold float64 // local map coverage (0-1) below which we shorten our moves.  0 disables caution
+	CautionStep      int     // the longest move we'll make into completely unknown territory
+	StartDir         string  // the direction we set off exploring in, or auto to pick one once we can see some of the map
+	ExploreBias      string  // which way to lean when bouncing around exploring: none, center or unexplored
+	Explore          bool    // when there's nothing to go after, head for the nearest edge of the known floor rather than bouncing around
+	PredictMotion    bool    // aim and approach from where we expect to be once our commands land
+	EnemyHistory     int     // how many recent sightings of each enemy to estimate its velocity from.  Below 2 disables
+	DiagonalCost     float64 // what the pathfinder counts a diagonal step as, with a straight one costing 1.  0 only moves straight, diagonalByMetric goes by DistanceMetric
+	DistanceMetric   string  // euclidean, manhattan or chebyshev: how we measure the way to targets, and what the pathfinder counts a diagonal as
+	WarmupTicks      int     // explore for this many ticks before going after anything.  0 for no limit
+	WarmupCoverage   float64 // or until we know this fraction of the map around us.  Both 0 disables warmup
+	MaxMapCells      int     // the most walls and floors to remember, 0 for no limit
+	EvictPolicy      string  // which to forget when there are too many: farthest from us or oldest
+	TileSize         int     // size of a map tile, until we've worked out the server's from what it sends us
+	MoveStep         int     // how far each exploration moveto goes, for tiles of TileSize.  Scaled if tiles turn out bigger or smaller
+
+	RoundStartMsg   string // message the server sends when a new round begins
+	RoundEndMsg     string // message the server sends when a round is over
+	ResetMapOnRound bool   // forget walls and floors between rounds, for arenas that regenerate
+	RejoinOnRound   bool   // send requestjoin again when a new round starts
+
+	Scorer           Scorer        // rates candidate targets, nil for defaultScore
+	Script           []ScriptStep  // objectives to work through in order before using our own judgement
+	ScriptTimeout    time.Duration // how long to give each scripted step, 0 for as long as it takes
+	ClusterRadius    int           // items this close together are collected as a group.  0 treats every item on its own
+	RememberSpawns   bool          // remember everywhere ammo and food turns up, to go back and look when there's none in sight
+	PathDistance     bool          // rank items by how far we'd have to walk to them rather than the straight line distance
+	Weighted         bool          // choose between the key, exit, ammo, food and enemies by weighing them up, rather than in a fixed order
+	Weights          TargetWeights // how, with Weighted
+	Trace            bool          // log the reasoning behind every decision
+	TickMs           int           // how long each tick of the write loop lasts, in milliseconds
+	JitterMs         int           // randomly lengthen or shorten each tick by up to this much so our bots don't move in lockstep (less than TickMs)
+	Seed             int64
+	Clock            Clock         // where the time comes from, nil for the real clock.  Tests use a FakeClock
+	MaxRuntime       time.Duration // stop playing after this long.  0 runs forever
+	ReconnectTimeout time.Duration // reconnect if the server sends no playerupdate for this long.  0 never does
+	Resilient        bool          // log panics in the read and write loops and carry on rather than crashing
+	DryRun           bool          // log the commands we would send rather than sending them
+	MaxPPS           int           // most packets to send a second, dropping any more.  0 for no limit
+	Capture          io.Writer     // if set, every datagram sent or received is written here
+	Record           io.Writer     // if set, every datagram received is written here, for -replay
+	MetricsAddr      string        // if set, serve Prometheus metrics and the /override and /trace controls on this address, e.g. :9100
+}
+
+// DefaultConfig is how the bot plays if nobody tells it otherwise
+func DefaultConfig() Config {
+	return Config{
+		Host:             "127.0.0.1",
+		Port:             11000,
+		Network:          "udp",
+		Name:             "dvdbot",
+		Colors:           map[string]string{"warrior": "red", "valkyrie": "blue", "elf": "green", "wizard": "yellow"},
+		ShotDelay:        2,
+		FireCone:         22.5,
+		RepositionTicks:  10,
+		AlignWindow:      10,
+		CombatProfile:    "aggressive",
+		Strategy:         "default",
+		KiteDistance:     80,
+		ExitGuard:        100,
+		PanicDistance:    40,
+		EnemyTTL:         3 * time.Second,
+		StrafeTicks:      3,
+		WallTTL:          60 * time.Second,
+		AmmoTTL:          5 * time.Second,
+		Weights:          DefaultWeights(),
+		FoodTTL:          5 * time.Second,
+		AggressionCurve:  1,
+		EnemyHistory:     8,
+		ExitNeedsKey:     true,
+		KeyGiveUp:        "frag",
+		FleeHealth:       2,
+		EvadeHealth:      2,
+		ResumeHealth:     2,
+		FleeAmmo:         1,
+		ResumeAmmo:       1,
+		Teammates:        make(map[string]bool),
+		CautionStep:      20,
+		DiagonalCost:     diagonalByMetric,
+		DistanceMetric:   "euclidean",
+		EvictPolicy:      "farthest",
+		TileSize:         8,
+		MoveStep:         10,
+		StartDir:         "ne",
+		ExploreBias:      "none",
+		RoundStartMsg:    "roundstart",
+		RoundEndMsg:      "roundend",
+		TickMs:           100,
+		Seed:             1,
+		ReconnectTimeout: 10 * time.Second,
+	}
+}
+
+// Validate checks the settings that only accept a fixed set or range of values
+func (c Config) Validate() error {
+	if c.StartDir != "ne" && c.StartDir != "se" && c.StartDir != "sw" && c.StartDir != "nw" && c.StartDir != "auto" {
+		return fmt.Errorf("unknown start direction %q", c.StartDir)
+	}
+	if c.Strategy != "default" && c.Strategy != "collector" {
+		return fmt.Errorf("unknown strategy %q", c.Strategy)
+	}
+	if c.CombatProfile != "aggressive" && c.CombatProfile != "kite" {
+		return fmt.Errorf("unknown combat profile %q", c.CombatProfile)
+	}
+	if c.ExploreBias != "none" && c.ExploreBias != "center" && c.ExploreBias != "unexplored" {
+		return fmt.Errorf("unknown exploration bias %q", c.ExploreBias)
+	}
+	if _, ok := c.Colors[c.Class]; c.Class != "" && !ok {
+		return fmt.Errorf("unknown class %q, we don't know its key colour", c.Class)
+	}
+	if c.Network != "udp" && c.Network != "udp4" && c.Network != "udp6" {
+		return fmt.Errorf("unknown network %q, want udp, udp4 or udp6", c.Network)
+	}
+	if c.KeyGiveUp != "exit" && c.KeyGiveUp != "frag" && c.KeyGiveUp != "keep" {
+		return fmt.Errorf("unknown key give up strategy %q", c.KeyGiveUp)
+	}
+	if c.EngageDistance < 0 || c.AggressionHealth < 0 || c.AggressionCurve <= 0 || c.MinAggression < 0 || c.MinAggression > 1 {
+		return fmt.Errorf("engage distance and aggression health can't be negative, aggression curve must be positive and min aggression between 0 and 1")
+	}
+	if c.ResumeHealth < c.FleeHealth || c.ResumeAmmo < c.FleeAmmo {
+		return fmt.Errorf("resume thresholds must be at least the flee thresholds")
+	}
+	if c.ExitGuard < 0 {
+		return fmt.Errorf("exit guard distance can't be negative")
+	}
+	if c.MaxShootRange < 0 {
+		return fmt.Errorf("max shoot range can't be negative")
+	}
+	if c.EvadeHealth < 0 {
+		return fmt.Errorf("evade health can't be negative")
+	}
+	if c.ClusterRadius < 0 {
+		return fmt.Errorf("cluster radius can't be negative")
+	}
+	if c.WarmupTicks < 0 || c.WarmupCoverage < 0 || c.WarmupCoverage > 1 {
+		return fmt.Errorf("warmup ticks can't be negative and warmup coverage must be between 0 and 1")
+	}
+	if c.CautionThreshold < 0 || c.CautionThreshold > 1 || c.CautionStep <= 0 {
+		return fmt.Errorf("caution threshold must be between 0 and 1 and caution step positive")
+	}
+	if c.DiagonalCost < 0 && c.DiagonalCost != diagonalByMetric {
+		return fmt.Errorf("diagonal cost can't be negative, other than %g to go by the distance metric", float64(diagonalByMetric))
+	}
+	if c.DistanceMetric != "euclidean" && c.DistanceMetric != "manhattan" && c.DistanceMetric != "chebyshev" {
+		return fmt.Errorf("unknown distance metric %q", c.DistanceMetric)
+	}
+	if c.MaxMapCells < 0 {
+		return fmt.Errorf("max map cells can't be negative")
+	}
+	if c.EvictPolicy != "farthest" && c.EvictPolicy != "oldest" {
+		return fmt.Errorf("unknown eviction policy %q", c.EvictPolicy)
+	}
+	if c.WallRadius < 0 {
+		return fmt.Errorf("wall radius can't be negative")
+	}
+	if c.WallTTL < 0 || c.AmmoTTL < 0 || c.FoodTTL < 0 {
+		return fmt.Errorf("wall, ammo and food ttls can't be negative")
+	}
+	if w := c.Weights; w.Key < 0 || w.Exit < 0 || w.Ammo < 0 || w.Food < 0 || w.Enemy < 0 || w.DistanceScale <= 0 {
+		return fmt.Errorf("target weights can't be negative and the distance scale must be positive")
+	}
+	if c.StrafeTicks < 0 {
+		return fmt.Errorf("strafe ticks can't be negative")
+	}
+	if c.MaxPPS < 0 {
+		return fmt.Errorf("max packets per second can't be negative")
+	}
+	if c.ShotDelay < 0 {
+		return fmt.Errorf("shot delay can't be negative")
+	}
+	if c.JitterMs < 0 || (c.JitterMs > 0 && c.JitterMs >= c.TickMs) {
+		return fmt.Errorf("jitter can't be negative and must be less than the tick")
+	}
+	if c.TileSize <= 0 || c.MoveStep <= 0 {
+		return fmt.Errorf("tile size and move step must be positive")
+	}
+	return nil
+}
+
+// KeyColor is the colour of our key: that of Class if it's set, otherwise of the class we're named after
+func (c Config) KeyColor() (string, bool) {
+	class := c.Class
+	if class == "" {
+		class = c.Name
+	}
+	color, ok := c.Colors[class]
+	return color, ok
+}
+
+// ParseWeights sets target weights from a comma separated list of name:weight pairs,
+// e.g. key:3,food:2,distance:50.  Weights not in the list are left as they are
+func (c *Config) ParseWeights(list string) error {
+	fields := map[string]*float64{
+		"key":      &c.Weights.Key,
+		"exit":     &c.Weights.Exit,
+		"ammo":     &c.Weights.Ammo,
+		"food":     &c.Weights.Food,
+		"enemy":    &c.Weights.Enemy,
+		"distance": &c.Weights.DistanceScale,
+	}
+	for _, pair := range strings.Split(list, ",") {
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, ":")
+		field, known := fields[name]
+		if !ok || !known {
+			return fmt.Errorf("bad target weight %q, want key, exit, ammo, food, enemy or distance:number", pair)
+		}
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("bad target weight %q: %v", pair, err)
+		}
+		*field = weight
+	}
+	return nil
+}
+
+// ParseColors adds class:color pairs from a comma separated list to the colour map, replacing existing classes
+func (c *Config) ParseColors(list string) error {
+	for _, pair := range strings.Split(list, ",") {
+		if pair == "" {
+			continue
+		}
+		class, color, ok := strings.Cut(pair, ":")
+		if !ok || class == "" || color == "" {
+			return fmt.Errorf("bad class colour %q, want class:color", pair)
+		}
+		c.Colors[class] = color
+	}
+	return nil
+}
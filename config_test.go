@@ -0,0 +1,107 @@
+package gauntletbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseColors(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    map[string]string // the classes changed from the defaults
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"archer:purple", map[string]string{"archer": "purple"}, false},
+		{"archer:purple,warrior:black", map[string]string{"archer": "purple", "warrior": "black"}, false},
+		{"archer:purple,", map[string]string{"archer": "purple"}, false},
+		{"archer", nil, true},
+		{"archer:", nil, true},
+		{":purple", nil, true},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		err := cfg.ParseColors(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseColors(%q) = %v, want an error %v", tt.list, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		want := DefaultConfig().Colors
+		for class, color := range tt.want {
+			want[class] = color
+		}
+		if !reflect.DeepEqual(cfg.Colors, want) {
+			t.Errorf("ParseColors(%q) made %v, want %v", tt.list, cfg.Colors, want)
+		}
+	}
+}
+
+func TestKeyColor(t *testing.T) {
+	tests := []struct {
+		name, class string
+		want        string
+		ok          bool
+	}{
+		{"warrior", "", "red", true},
+		{"elf", "", "green", true},
+		{"bob", "wizard", "yellow", true}, // the class over the name
+		{"warrior", "archer", "purple", true},
+		{"bob", "", "", false},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.Name, cfg.Class = tt.name, tt.class
+		if err := cfg.ParseColors("archer:purple"); err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := cfg.KeyColor(); got != tt.want || ok != tt.ok {
+			t.Errorf("name %s, class %q: KeyColor() = %q, %v, want %q, %v", tt.name, tt.class, got, ok, tt.want, tt.ok)
+		}
+	}
+	cfg := DefaultConfig()
+	cfg.Class = "archer"
+	if cfg.Validate() == nil {
+		t.Error("accepted a class with no key colour")
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    TargetWeights
+		wantErr bool
+		valid   bool
+	}{
+		{"", DefaultWeights(), false, true},
+		{"key:5,food:2.5", TargetWeights{Key: 5, Exit: 4, Ammo: 1, Food: 2.5, Enemy: 1.5, DistanceScale: 100}, false, true},
+		{"distance:50,", TargetWeights{Key: 3, Exit: 4, Ammo: 1, Food: 1, Enemy: 1.5, DistanceScale: 50}, false, true},
+		{"enemy:0", TargetWeights{Key: 3, Exit: 4, Ammo: 1, Food: 1, Enemy: 0, DistanceScale: 100}, false, true},
+		// parse, but Validate won't have them
+		{"ammo:-1", TargetWeights{Key: 3, Exit: 4, Ammo: -1, Food: 1, Enemy: 1.5, DistanceScale: 100}, false, false},
+		{"distance:0", TargetWeights{Key: 3, Exit: 4, Ammo: 1, Food: 1, Enemy: 1.5, DistanceScale: 0}, false, false},
+		{"scroll:2", TargetWeights{}, true, false},
+		{"key", TargetWeights{}, true, false},
+		{"key:lots", TargetWeights{}, true, false},
+		{"key:", TargetWeights{}, true, false},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		err := cfg.ParseWeights(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseWeights(%q) = %v, want an error %v", tt.list, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if cfg.Weights != tt.want {
+			t.Errorf("ParseWeights(%q) made %+v, want %+v", tt.list, cfg.Weights, tt.want)
+		}
+		if valid := cfg.Validate() == nil; valid != tt.valid {
+			t.Errorf("ParseWeights(%q) then Validate: valid %v, want %v", tt.list, valid, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// POST /override?goal=goto&x=100&y=200&ttl=30s has the bot head for a location, or with goal=fight go after
+// the nearest enemy, until the ttl runs out.  DELETE /override hands the choice back to the bot
+func (b *Bot) handleOverride(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		goal, loc, ttl, err := parseOverride(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := b.Override(goal, loc, ttl); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		Infof("Overridden from %s: %s (%d,%d) for %s", r.RemoteAddr, goal, loc.X, loc.Y, ttl)
+	case http.MethodDelete:
+		b.ClearOverride()
+		Infof("Override cleared from %s", r.RemoteAddr)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "POST to override, DELETE to clear", http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// the goal, location and ttl of an override request.  goto needs a location, fight doesn't take one
+func parseOverride(r *http.Request) (string, Loc, time.Duration, error) {
+	goal := r.FormValue("goal")
+	if goal != "goto" && goal != "fight" {
+		return "", Loc{}, 0, fmt.Errorf("unknown goal %q, want goto or fight", goal)
+	}
+	ttl, err := time.ParseDuration(r.FormValue("ttl"))
+	if err != nil || ttl <= 0 {
+		return "", Loc{}, 0, fmt.Errorf("bad ttl %q, want a positive duration such as 30s", r.FormValue("ttl"))
+	}
+	var loc Loc
+	if goal == "goto" {
+		x, xOK := parseCoord(r.FormValue("x"))
+		y, yOK := parseCoord(r.FormValue("y"))
+		if !xOK || !yOK {
+			return "", Loc{}, 0, fmt.Errorf("bad location (%q,%q) to goto", r.FormValue("x"), r.FormValue("y"))
+		}
+		loc = Loc{X: x, Y: y}
+	} else if r.FormValue("x") != "" || r.FormValue("y") != "" {
+		return "", Loc{}, 0, fmt.Errorf("fight doesn't take a location")
+	}
+	return goal, loc, ttl, nil
+}
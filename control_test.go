@@ -0,0 +1,94 @@
+package gauntletbot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// send a request to the monitor API, returning the status it answered with
+func monitorRequest(b *Bot, method string, target string) int {
+	rec := httptest.NewRecorder()
+	b.monitorHandler().ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec.Code
+}
+
+func TestOverrideEndpoint(t *testing.T) {
+	b, clock := newTestBot(nil)
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	for _, tt := range []struct {
+		method string
+		target string
+		want   int
+	}{
+		{http.MethodGet, "/override", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/override?goal=dance&ttl=5s", http.StatusBadRequest},
+		{http.MethodPost, "/override?goal=goto&x=300&y=100", http.StatusBadRequest},
+		{http.MethodPost, "/override?goal=goto&x=300&y=100&ttl=-5s", http.StatusBadRequest},
+		{http.MethodPost, "/override?goal=goto&x=300&y=100&ttl=soon", http.StatusBadRequest},
+		{http.MethodPost, "/override?goal=goto&x=300&ttl=5s", http.StatusBadRequest},
+		{http.MethodPost, "/override?goal=goto&x=300&y=1e9&ttl=5s", http.StatusBadRequest},
+		{http.MethodPost, "/override?goal=fight&x=300&y=100&ttl=5s", http.StatusBadRequest},
+	} {
+		if got := monitorRequest(b, tt.method, tt.target); got != tt.want {
+			t.Errorf("%s %s answered %d, want %d", tt.method, tt.target, got, tt.want)
+		}
+		if o := b.activeOverride(); o != nil {
+			t.Fatalf("%s %s left override %+v in force", tt.method, tt.target, *o)
+		}
+	}
+
+	if got := monitorRequest(b, http.MethodPost, "/override?goal=goto&x=300&y=100&ttl=5s"); got != http.StatusNoContent {
+		t.Fatalf("goto answered %d, want %d", got, http.StatusNoContent)
+	}
+	ts := &tickState{dir: "ne", target: "key", shooter: NewShooter(0)}
+	clock.Advance(4 * time.Second)
+	b.tick(nopSender{}, ts)
+	if ts.target != "override" || b.moveGoal == nil || b.moveGoal.X <= 100 {
+		t.Fatalf("went for %s to %v, want to follow the override towards (300,100)", ts.target, b.moveGoal)
+	}
+	clock.Advance(time.Second)
+	b.tick(nopSender{}, ts)
+	if ts.target == "override" {
+		t.Error("still following the override after its ttl")
+	}
+
+	// fight takes over from our own judgement until it's cleared
+	if got := monitorRequest(b, http.MethodPost, "/override?goal=fight&ttl=1m"); got != http.StatusNoContent {
+		t.Fatalf("fight answered %d, want %d", got, http.StatusNoContent)
+	}
+	if o := b.activeOverride(); o == nil || o.Goal != "fight" {
+		t.Fatalf("override %v after asking to fight", o)
+	}
+	if got := monitorRequest(b, http.MethodDelete, "/override"); got != http.StatusNoContent {
+		t.Fatalf("clearing answered %d, want %d", got, http.StatusNoContent)
+	}
+	if o := b.activeOverride(); o != nil {
+		t.Errorf("override %+v still in force after it was cleared", *o)
+	}
+}
+
+func TestTraceEndpoint(t *testing.T) {
+	b, _ := newTestBot(nil)
+	for _, tt := range []struct {
+		method string
+		target string
+		want   int
+		on     bool
+	}{
+		{http.MethodPost, "/trace?on=1", http.StatusNoContent, true},
+		{http.MethodGet, "/trace?on=0", http.StatusMethodNotAllowed, true},
+		{http.MethodPost, "/trace?on=maybe", http.StatusBadRequest, true},
+		{http.MethodPost, "/trace", http.StatusBadRequest, true},
+		{http.MethodPost, "/trace?on=0", http.StatusNoContent, false},
+		{http.MethodPost, "/trace?on=true", http.StatusNoContent, true},
+	} {
+		if got := monitorRequest(b, tt.method, tt.target); got != tt.want {
+			t.Errorf("%s %s answered %d, want %d", tt.method, tt.target, got, tt.want)
+		}
+		if got := b.tracing.Load(); got != tt.on {
+			t.Errorf("after %s %s tracing is %v, want %v", tt.method, tt.target, got, tt.on)
+		}
+	}
+}
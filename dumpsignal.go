@@ -0,0 +1,32 @@
+//go:build !windows
+
+package gauntletbot
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// DumpOnSignal writes a snapshot of each bot to its own timestamped JSON file in the working directory
+// whenever we get a SIGUSR1, until the context is cancelled
+func DumpOnSignal(ctx context.Context, bots ...*Bot) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			for _, b := range bots {
+				if path, err := b.dumpSnapshot(); err != nil {
+					Errorf("Couldn't write a state snapshot: %v", err)
+				} else {
+					Infof("Wrote a state snapshot to %s", path)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package gauntletbot
+
+import "context"
+
+// DumpOnSignal does nothing on Windows, which has no SIGUSR1 to ask for a snapshot with
+func DumpOnSignal(ctx context.Context, bots ...*Bot) {}
@@ -0,0 +1,65 @@
+package gauntletbot_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	gauntletbot "github.com/neilo40/gauntletBot"
+)
+
+// a strategy from outside the package, which stops the bot once it knows where it is
+type stopWhenPlaced struct {
+	stop   context.CancelFunc
+	placed chan gauntletbot.Loc
+}
+
+func (s stopWhenPlaced) Decide(snap gauntletbot.Snapshot) gauntletbot.Action {
+	if snap.Player.Loc != (gauntletbot.Loc{}) {
+		select {
+		case s.placed <- snap.Player.Loc:
+			s.stop()
+		default:
+		}
+	}
+	return gauntletbot.Action{Goal: "goto", Loc: snap.Player.Loc}
+}
+
+func TestEmbedWithOwnStrategy(t *testing.T) {
+	cfg := gauntletbot.DefaultConfig()
+	cfg.Name = "warrior"
+	cfg.ReconnectTimeout = 0
+	cfg.DryRun = true
+	bot := gauntletbot.NewBot(cfg)
+	// the server tells us where we are, then says nothing more for a while
+	start := time.Now()
+	recording := strings.Join([]string{
+		start.Format("2006-01-02T15:04:05.000000Z07:00") + ` in 28 "playerupdate:10,20,5,3,False"`,
+		start.Add(5*time.Second).Format("2006-01-02T15:04:05.000000Z07:00") + ` in 10 "exit:40,60"`,
+	}, "\n")
+	if err := bot.Replay(strings.NewReader(recording)); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	placed := make(chan gauntletbot.Loc, 1)
+	bot.SetStrategy(stopWhenPlaced{stop: cancel, placed: placed})
+
+	done := make(chan error)
+	go func() { done <- bot.Run(ctx) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(4 * time.Second):
+		t.Fatal("our strategy never stopped the bot")
+	}
+	if loc := <-placed; loc != (gauntletbot.Loc{X: 10, Y: 20}) {
+		t.Errorf("our strategy saw us at %v, want (10,20)", loc)
+	}
+	if p := bot.Player(); p.Loc != (gauntletbot.Loc{X: 10, Y: 20}) || p.Exit != nil {
+		t.Errorf("the bot ended up knowing %+v, want only where it is", p)
+	}
+}
@@ -0,0 +1,57 @@
+package gauntletbot
+
+import (
+	"sort"
+)
+
+// record that we've just been told about a map cell and, if that takes us over MaxMapCells, forget some.
+// Must be called with the wall and floor locks held.  Returns whether anything was evicted, in which case
+// the caller should drop the sight line cache once it's let go of the locks
+func (b *Bot) noteCell(x int, y int) bool {
+	if b.cfg.MaxMapCells == 0 {
+		return false
+	}
+	b.cellSeen[Loc{X: x, Y: y}] = b.clock.Now()
+	if len(b.cellSeen) <= b.cfg.MaxMapCells {
+		return false
+	}
+	b.evictCells()
+	return true
+}
+
+// trim the map back to 90% of MaxMapCells, so we aren't evicting on every new cell.  Either the cells
+// furthest from us or the ones we heard about longest ago go, depending on EvictPolicy
+func (b *Bot) evictCells() {
+	cells := make([]Loc, 0, len(b.cellSeen))
+	for cell := range b.cellSeen {
+		cells = append(cells, cell)
+	}
+	if b.cfg.EvictPolicy == "oldest" {
+		sort.Slice(cells, func(i, j int) bool {
+			return b.cellSeen[cells[i]].Before(b.cellSeen[cells[j]])
+		})
+	} else {
+		here := b.snapshotPlayer().Loc
+		sort.Slice(cells, func(i, j int) bool {
+			return distanceBetween(here, cells[i]) > distanceBetween(here, cells[j])
+		})
+	}
+	which := "furthest away"
+	if b.cfg.EvictPolicy == "oldest" {
+		which = "oldest"
+	}
+	evict := len(cells) - b.cfg.MaxMapCells*9/10
+	for _, cell := range cells[:evict] {
+		delete(b.cellSeen, cell)
+		delete(b.state.Walls[cell.X], cell.Y)
+		b.unindexWall(cell.X, cell.Y)
+		delete(b.state.Floor[cell.X], cell.Y)
+		if len(b.state.Walls[cell.X]) == 0 {
+			delete(b.state.Walls, cell.X)
+		}
+		if len(b.state.Floor[cell.X]) == 0 {
+			delete(b.state.Floor, cell.X)
+		}
+	}
+	Infof("Map reached %d cells, forgot the %d %s", len(cells), evict, which)
+}
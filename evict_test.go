@@ -0,0 +1,85 @@
+package gauntletbot
+
+import (
+	"testing"
+	"time"
+)
+
+// check the wall index and cellSeen agree with the walls and floors in the map
+func checkMapConsistent(t *testing.T, b *Bot) {
+	t.Helper()
+	b.wallMutex.Lock()
+	defer b.wallMutex.Unlock()
+	b.floorMutex.Lock()
+	defer b.floorMutex.Unlock()
+	cells := 0
+	for x, column := range b.state.Walls {
+		for y := range column {
+			cells++
+			wall := Loc{X: x, Y: y}
+			if _, ok := b.wallIndex[bucketOf(wall)][wall]; !ok {
+				t.Errorf("wall %v isn't in the index", wall)
+			}
+			if _, ok := b.cellSeen[wall]; !ok && b.cfg.MaxMapCells > 0 {
+				t.Errorf("wall %v isn't in cellSeen", wall)
+			}
+		}
+	}
+	for _, walls := range b.wallIndex {
+		for wall := range walls {
+			if !b.state.Walls[wall.X][wall.Y] {
+				t.Errorf("the index has %v, which isn't a wall", wall)
+			}
+		}
+	}
+	for _, column := range b.state.Floor {
+		cells += len(column)
+	}
+	if b.cfg.MaxMapCells > 0 && len(b.cellSeen) != cells {
+		t.Errorf("cellSeen has %d cells, the map %d", len(b.cellSeen), cells)
+	}
+}
+
+func TestEvictOldest(t *testing.T) {
+	b, clock := newTestBot(func(cfg *Config) {
+		cfg.MaxMapCells = 20
+		cfg.EvictPolicy = "oldest"
+	})
+	// a wall and then a floor down the row, one a second, so the leftmost are the oldest
+	for i := 0; i < 30; i++ {
+		if i%2 == 0 {
+			b.setWall(i*8, 0)
+		} else {
+			b.setFloor(i*8, 0)
+		}
+		clock.Advance(time.Second)
+		checkMapConsistent(t, b)
+	}
+	s := b.Snapshot()
+	if cells := s.Walls + s.Floors; cells > 20 {
+		t.Fatalf("remember %d cells, more than the 20 allowed", cells)
+	}
+	// each time the map goes over 20 the 3 oldest go to bring it back to 18, which by the 30th is the first 12
+	for i := 0; i < 30; i++ {
+		known := b.state.Walls[i*8][0] || b.state.Floor[i*8][0]
+		if want := i >= 12; known != want {
+			t.Errorf("cell %d: remembered %v, want %v", i, known, want)
+		}
+	}
+}
+
+func TestExpiredWallsLeaveCellSeen(t *testing.T) {
+	b, clock := newTestBot(func(cfg *Config) {
+		cfg.MaxMapCells = 100
+		cfg.WallTTL = 10 * time.Second
+	})
+	b.setWall(0, 0)
+	b.setFloor(8, 0)
+	clock.Advance(11 * time.Second)
+	b.setWall(16, 0)
+	b.expireWalls()
+	checkMapConsistent(t, b)
+	if b.state.Walls[0][0] || !b.state.Walls[16][0] {
+		t.Error("expired the wrong walls")
+	}
+}
@@ -0,0 +1,297 @@
+package gauntletbot
+
+import (
+	"math"
+)
+
+// what fraction of the tiles around a point do we know to be either wall or floor?
+func (b *Bot) localCoverage(loc Loc) float64 {
+	return b.coverageWithin(loc, cautionRadius)
+}
+
+// the fraction of tiles within radius tiles of a point, on both axes, that we know about
+func (b *Bot) coverageWithin(loc Loc, radius int) float64 {
+	reach := radius * b.tileSize()
+	known := 0
+	b.wallMutex.Lock()
+	known += countWithin(b.state.Walls, loc, reach)
+	b.wallMutex.Unlock()
+	b.floorMutex.Lock()
+	known += countWithin(b.state.Floor, loc, reach)
+	b.floorMutex.Unlock()
+	side := 2*radius + 1
+	coverage := float64(known) / float64(side*side)
+	return math.Min(coverage, 1)
+}
+
+// count the tiles in a map that are within reach of a point on both axes
+func countWithin(tiles map[int]map[int]bool, loc Loc, reach int) int {
+	count := 0
+	for x := range tiles {
+		if x < loc.X-reach || x > loc.X+reach {
+			continue
+		}
+		for y, set := range tiles[x] {
+			if set && y >= loc.Y-reach && y <= loc.Y+reach {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// until we know enough of the map around us, don't charge straight at far away targets.
+// the allowed step grows as coverage approaches the caution threshold, then is unlimited
+func (b *Bot) cautiousStep(from Loc, to Loc) Loc {
+	if b.cfg.CautionThreshold <= 0 {
+		return to
+	}
+	coverage := b.localCoverage(from)
+	if coverage >= b.cfg.CautionThreshold {
+		return to
+	}
+	maxStep := float64(b.cfg.CautionStep) / (1 - coverage/b.cfg.CautionThreshold)
+	dx := float64(to.X - from.X)
+	dy := float64(to.Y - from.Y)
+	dist := math.Hypot(dx, dy)
+	if dist <= maxStep {
+		return to
+	}
+	scale := maxStep / dist
+	return Loc{X: from.X + int(dx*scale), Y: from.Y + int(dy*scale)}
+}
+
+// pick a new direction to go in - if we hit a wall, bounce at a 90 degree angle
+// needs to check if the wall we encountered (our position didn't change in one or both axes) was horizontal or vertical.
+// Moving diagonally, only the blocked axis flips; moving straight along an axis there's only one it can be, so we turn round.
+// sensitivity allows for jitter: a position that varied by less than this counts as stationary
+func newDirection(oldDir string, lastLoc Loc, currentLoc Loc, sensitivity int) string {
+	xUnchanged := lastLoc.X < (currentLoc.X+sensitivity) && lastLoc.X > (currentLoc.X-sensitivity)
+	yUnchanged := lastLoc.Y < (currentLoc.Y+sensitivity) && lastLoc.Y > (currentLoc.Y-sensitivity)
+	newDir := oldDir
+	switch oldDir {
+	case "n", "s":
+		// x never changes going north or south, so only y tells us we're blocked
+		if yUnchanged {
+			newDir = rotate(oldDir, 4)
+		}
+	case "e", "w":
+		if xUnchanged {
+			newDir = rotate(oldDir, 4)
+		}
+	}
+	if xUnchanged {
+		switch oldDir {
+		case "ne":
+			newDir = "nw"
+		case "se":
+			newDir = "sw"
+		case "sw":
+			newDir = "se"
+		case "nw":
+			newDir = "ne"
+		}
+	} else if yUnchanged {
+		switch oldDir {
+		case "ne":
+			newDir = "se"
+		case "se":
+			newDir = "ne"
+		case "sw":
+			newDir = "nw"
+		case "nw":
+			newDir = "sw"
+		}
+	}
+	return newDir
+}
+
+// after a bounce, only the blocked axis has to flip.  Steer the other axis towards our preferred part of the map
+func (b *Bot) biasDirection(oldDir string, newDir string, loc Loc) string {
+	if b.cfg.ExploreBias == "none" || newDir == oldDir || len(newDir) != 2 {
+		// turning round from a straight move leaves no other axis to steer
+		return newDir
+	}
+	goal, ok := b.exploreGoal(loc)
+	if !ok {
+		return newDir
+	}
+	ns := newDir[:1]
+	ew := newDir[1:]
+	if oldDir[1:] != newDir[1:] {
+		// x was blocked, so north/south is ours to choose
+		if goal.Y > loc.Y {
+			ns = "s"
+		} else if goal.Y < loc.Y {
+			ns = "n"
+		}
+	} else {
+		// y was blocked, so east/west is ours to choose
+		if goal.X > loc.X {
+			ew = "e"
+		} else if goal.X < loc.X {
+			ew = "w"
+		}
+	}
+	return ns + ew
+}
+
+// head for whichever diagonal quadrant between us and the edges of the known map has the most room in it
+func (b *Bot) spawnDirection(loc Loc) (string, bool) {
+	lo, hi, ok := b.mapBounds()
+	if !ok {
+		return "", false
+	}
+	east := math.Max(0, float64(hi.X-loc.X))
+	west := math.Max(0, float64(loc.X-lo.X))
+	north := math.Max(0, float64(loc.Y-lo.Y))
+	south := math.Max(0, float64(hi.Y-loc.Y))
+	areas := map[string]float64{"ne": north * east, "se": south * east, "sw": south * west, "nw": north * west}
+	best := "ne"
+	for _, dir := range []string{"se", "sw", "nw"} {
+		if areas[dir] > areas[best] {
+			best = dir
+		}
+	}
+	return best, true
+}
+
+// where would we like exploration to take us?
+func (b *Bot) exploreGoal(loc Loc) (Loc, bool) {
+	switch b.cfg.ExploreBias {
+	case "center":
+		lo, hi, ok := b.mapBounds()
+		if !ok {
+			return Loc{}, false
+		}
+		return Loc{X: lo.X + (hi.X-lo.X)/2, Y: lo.Y + (hi.Y-lo.Y)/2}, true
+	case "unexplored":
+		return b.leastKnownQuadrant(loc), true
+	}
+	return Loc{}, false
+}
+
+// the smallest box containing every wall and floor tile we know about
+func (b *Bot) mapBounds() (Loc, Loc, bool) {
+	lo := Loc{X: math.MaxInt, Y: math.MaxInt}
+	hi := Loc{X: math.MinInt, Y: math.MinInt}
+	found := false
+	grow := func(tiles map[int]map[int]bool) {
+		for x := range tiles {
+			for y, set := range tiles[x] {
+				if !set {
+					continue
+				}
+				found = true
+				if x < lo.X {
+					lo.X = x
+				}
+				if x > hi.X {
+					hi.X = x
+				}
+				if y < lo.Y {
+					lo.Y = y
+				}
+				if y > hi.Y {
+					hi.Y = y
+				}
+			}
+		}
+	}
+	b.wallMutex.Lock()
+	grow(b.state.Walls)
+	b.wallMutex.Unlock()
+	b.floorMutex.Lock()
+	grow(b.state.Floor)
+	b.floorMutex.Unlock()
+	return lo, hi, found
+}
+
+// pick the diagonal quadrant around us that we know the least about, and return a point out in it
+func (b *Bot) leastKnownQuadrant(loc Loc) Loc {
+	reach := exploreRadius * b.tileSize()
+	counts := make(map[Loc]int)
+	count := func(tiles map[int]map[int]bool) {
+		for x := range tiles {
+			if x < loc.X-reach || x > loc.X+reach {
+				continue
+			}
+			for y, set := range tiles[x] {
+				if set && y >= loc.Y-reach && y <= loc.Y+reach {
+					counts[quadrant(loc, Loc{X: x, Y: y})]++
+				}
+			}
+		}
+	}
+	b.wallMutex.Lock()
+	count(b.state.Walls)
+	b.wallMutex.Unlock()
+	b.floorMutex.Lock()
+	count(b.state.Floor)
+	b.floorMutex.Unlock()
+
+	best := Loc{X: 1, Y: -1}
+	for _, q := range []Loc{{X: 1, Y: -1}, {X: 1, Y: 1}, {X: -1, Y: 1}, {X: -1, Y: -1}} {
+		if counts[q] < counts[best] {
+			best = q
+		}
+	}
+	return Loc{X: loc.X + best.X*reach, Y: loc.Y + best.Y*reach}
+}
+
+// which diagonal quadrant around the origin is a point in?  points on an axis go to the positive side
+func quadrant(origin Loc, point Loc) Loc {
+	q := Loc{X: 1, Y: 1}
+	if point.X < origin.X {
+		q.X = -1
+	}
+	if point.Y < origin.Y {
+		q.Y = -1
+	}
+	return q
+}
+
+// with nothing better to do, explore: with Explore, by heading for the nearest edge of the floor we know,
+// otherwise by carrying on in dir and bouncing off whatever we hit
+func (b *Bot) wander(here Loc, dir string) {
+	if b.cfg.Explore {
+		// wherever we've stood, we've seen all there is to see from
+		b.explored[b.cellOf(here)] = true
+		if frontier, ok := b.nearestFrontier(here); ok && b.approach(here, frontier) {
+			return
+		}
+	}
+	b.moveToDir(dir)
+}
+
+// the nearest tile of known floor on the frontier that we haven't already stood on
+func (b *Bot) nearestFrontier(here Loc) (Loc, bool) {
+	g := b.snapshotGrid(here, here)
+	var best Loc
+	bestDistance := math.Inf(1)
+	for cell, pos := range g.floor {
+		if b.explored[cell] || !g.onFrontier(cell) {
+			continue
+		}
+		if d := b.distance(here, pos); d < bestDistance {
+			best = pos
+			bestDistance = d
+		}
+	}
+	return best, !math.IsInf(bestDistance, 1)
+}
+
+// is a floor tile on the edge of what we know, next to a tile that's neither wall nor floor?  Only the
+// four straight neighbours count, as diagonals can't be walked into without cutting a corner
+func (g grid) onFrontier(cell Loc) bool {
+	for _, step := range []Loc{{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}} {
+		next := Loc{X: cell.X + step.X, Y: cell.Y + step.Y}
+		if g.walls[next] {
+			continue
+		}
+		if _, ok := g.floor[next]; !ok {
+			return true
+		}
+	}
+	return false
+}
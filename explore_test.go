@@ -0,0 +1,256 @@
+package gauntletbot
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// a diagonal with the north/south half (flipY) or the east/west half flipped, as a bounce off a wall would
+func flipAxis(dir string, flipY bool) string {
+	opposite := map[byte]string{'n': "s", 's': "n", 'e': "w", 'w': "e"}
+	if flipY {
+		return opposite[dir[0]] + dir[1:]
+	}
+	return dir[:1] + opposite[dir[1]]
+}
+
+func TestBiasDirectionTowardCentre(t *testing.T) {
+	diagonals := []string{"ne", "se", "sw", "nw"}
+	centre := Loc{X: 400, Y: 400}
+	towardCentre := func(bias string) int {
+		b, _ := newTestBot(func(cfg *Config) { cfg.ExploreBias = bias })
+		b.setWall(0, 0)
+		b.setWall(800, 800)
+		rng := rand.New(rand.NewSource(1))
+		toward := 0
+		for i := 0; i < 400; i++ {
+			loc := Loc{X: rng.Intn(801), Y: rng.Intn(801)}
+			oldDir := diagonals[rng.Intn(len(diagonals))]
+			blockedY := rng.Intn(2) == 0
+			dir := b.biasDirection(oldDir, flipAxis(oldDir, blockedY), loc)
+			// the axis the bounce didn't flip is the one the bias gets to steer
+			if blockedY {
+				toward += boolInt((dir[1] == 'e') == (centre.X > loc.X))
+			} else {
+				toward += boolInt((dir[0] == 's') == (centre.Y > loc.Y))
+			}
+		}
+		return toward
+	}
+	unbiased, biased := towardCentre("none"), towardCentre("center")
+	if biased <= unbiased || biased < 390 {
+		t.Errorf("headed for the centre after %d of 400 bounces with a centre bias and %d without", biased, unbiased)
+	}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestValidateCaution(t *testing.T) {
+	tests := []struct {
+		threshold float64
+		step      int
+		ok        bool
+	}{
+		{0, 20, true}, // caution off
+		{0.5, 20, true},
+		{1, 1, true},
+		{-0.1, 20, false},
+		{1.5, 20, false},
+		{0.5, 0, false},
+		{0.5, -20, false},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.CautionThreshold, cfg.CautionStep = tt.threshold, tt.step
+		if err := cfg.Validate(); (err == nil) != tt.ok {
+			t.Errorf("caution threshold %g, step %d: Validate() = %v, want ok %v", tt.threshold, tt.step, err, tt.ok)
+		}
+	}
+}
+
+func TestNewDirection(t *testing.T) {
+	from := Loc{X: 100, Y: 100}
+	tests := []struct {
+		dir  string
+		to   Loc
+		want string
+	}{
+		// blocked on both axes: diagonals flip x first, straight moves turn round
+		{"ne", from, "nw"},
+		{"sw", from, "se"},
+		{"n", from, "s"},
+		{"e", from, "w"},
+		// only the blocked axis flips
+		{"ne", Loc{X: 100, Y: 80}, "nw"},
+		{"ne", Loc{X: 120, Y: 100}, "se"},
+		{"se", Loc{X: 120, Y: 100}, "ne"},
+		{"nw", Loc{X: 80, Y: 100}, "sw"},
+		// moving along fine
+		{"ne", Loc{X: 120, Y: 80}, "ne"},
+		{"s", Loc{X: 100, Y: 120}, "s"},
+		{"w", Loc{X: 80, Y: 100}, "w"},
+		// a little jitter counts as not moving
+		{"e", Loc{X: 101, Y: 100}, "w"},
+		{"se", Loc{X: 120, Y: 98}, "ne"},
+	}
+	for _, tt := range tests {
+		if got := newDirection(tt.dir, from, tt.to, 3); got != tt.want {
+			t.Errorf("going %s from %v to %v: newDirection() = %s, want %s", tt.dir, from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestNearestFrontier(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.Explore = true })
+	at := func(x int, y int) Loc { return Loc{X: tileCentre(b, x), Y: tileCentre(b, y)} }
+	// a room of floor in tiles 1-5 each way, walled in on all sides but the east
+	for x := 0; x <= 6; x++ {
+		for y := 0; y <= 6; y++ {
+			switch {
+			case x >= 1 && x <= 5 && y >= 1 && y <= 5:
+				b.setFloor(at(x, y).X, at(x, y).Y)
+			case x < 6:
+				b.setWall(at(x, y).X, at(x, y).Y)
+			}
+		}
+	}
+	here := at(1, 1)
+	if got, ok := b.nearestFrontier(here); !ok || got != at(5, 1) {
+		t.Fatalf("nearestFrontier() = %v, %v, want %v on the open east side", got, ok, at(5, 1))
+	}
+	// once we've stood there, the next one along
+	b.explored[b.cellOf(at(5, 1))] = true
+	if got, ok := b.nearestFrontier(here); !ok || got != at(5, 2) {
+		t.Errorf("nearestFrontier() = %v, %v with (5,1) explored, want %v", got, ok, at(5, 2))
+	}
+	// wall the east side off too and there's nothing left to find
+	for y := 0; y <= 6; y++ {
+		b.setWall(at(6, y).X, at(6, y).Y)
+	}
+	if got, ok := b.nearestFrontier(here); ok {
+		t.Errorf("nearestFrontier() = %v in a room walled in all round, want none", got)
+	}
+}
+
+func TestSpawnDirection(t *testing.T) {
+	b, _ := newTestBot(nil)
+	if dir, ok := b.spawnDirection(Loc{X: 100, Y: 100}); ok {
+		t.Fatalf("spawnDirection() = %s knowing nothing of the map", dir)
+	}
+	b.setWall(0, 0)
+	b.setFloor(400, 400)
+	tests := []struct {
+		at   Loc
+		want string
+	}{
+		{Loc{X: 100, Y: 100}, "se"},
+		{Loc{X: 300, Y: 100}, "sw"},
+		{Loc{X: 300, Y: 300}, "nw"},
+		{Loc{X: 100, Y: 300}, "ne"},
+		{Loc{X: 200, Y: 200}, "ne"}, // all the same, so the first we try
+		{Loc{X: 500, Y: 100}, "sw"}, // off the east edge, so the room is all to the west
+		{Loc{X: 200, Y: -50}, "se"},
+	}
+	for _, tt := range tests {
+		if got, ok := b.spawnDirection(tt.at); !ok || got != tt.want {
+			t.Errorf("spawnDirection(%v) = %s, %v, want %s", tt.at, got, ok, tt.want)
+		}
+	}
+}
+
+func TestMapBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		walls  []Loc
+		floors []Loc
+		lo, hi Loc
+	}{
+		{"across the origin", []Loc{{X: -40, Y: -8}, {X: 16, Y: 24}}, nil, Loc{X: -40, Y: -8}, Loc{X: 16, Y: 24}},
+		{"all negative", []Loc{{X: -100, Y: -200}, {X: -300, Y: -50}}, []Loc{{X: -150, Y: -100}}, Loc{X: -300, Y: -200}, Loc{X: -100, Y: -50}},
+		{"floor further out than the walls", []Loc{{X: -8, Y: -8}}, []Loc{{X: -64, Y: 32}}, Loc{X: -64, Y: -8}, Loc{X: -8, Y: 32}},
+		{"as far out as we believe", []Loc{{X: -maxCoord, Y: maxCoord}}, []Loc{{X: maxCoord, Y: -maxCoord}},
+			Loc{X: -maxCoord, Y: -maxCoord}, Loc{X: maxCoord, Y: maxCoord}},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBot(nil)
+		for _, w := range tt.walls {
+			b.setWall(w.X, w.Y)
+		}
+		for _, f := range tt.floors {
+			b.setFloor(f.X, f.Y)
+		}
+		lo, hi, ok := b.mapBounds()
+		if !ok || lo != tt.lo || hi != tt.hi {
+			t.Errorf("%s: mapBounds() = %v, %v, %v, want %v, %v", tt.name, lo, hi, ok, tt.lo, tt.hi)
+		}
+	}
+}
+
+func TestSpawnDirectionFarOut(t *testing.T) {
+	tests := []struct {
+		name   string
+		corner []Loc // opposite corners of the known map
+		at     Loc
+		want   string
+	}{
+		{"all negative, in the north west", []Loc{{X: -400, Y: -400}, {X: 0, Y: 0}}, Loc{X: -300, Y: -300}, "se"},
+		{"all negative, in the north east", []Loc{{X: -400, Y: -400}, {X: 0, Y: 0}}, Loc{X: -100, Y: -300}, "sw"},
+		{"all negative, in the south east", []Loc{{X: -400, Y: -400}, {X: 0, Y: 0}}, Loc{X: -1, Y: -1}, "nw"},
+		{"huge, in the north east", []Loc{{X: -maxCoord, Y: -maxCoord}, {X: maxCoord, Y: maxCoord}}, Loc{X: maxCoord - 10, Y: -maxCoord + 10}, "sw"},
+		{"huge, in the south west", []Loc{{X: -maxCoord, Y: -maxCoord}, {X: maxCoord, Y: maxCoord}}, Loc{X: -maxCoord + 10, Y: maxCoord - 10}, "ne"},
+		{"huge, beyond the south east", []Loc{{X: -maxCoord, Y: -maxCoord}, {X: maxCoord, Y: maxCoord}}, Loc{X: 2 * maxCoord, Y: 2 * maxCoord}, "nw"},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBot(nil)
+		for _, w := range tt.corner {
+			b.setWall(w.X, w.Y)
+		}
+		if got, ok := b.spawnDirection(tt.at); !ok || got != tt.want {
+			t.Errorf("%s: spawnDirection(%v) = %s, %v, want %s", tt.name, tt.at, got, ok, tt.want)
+		}
+	}
+}
+
+func TestExploreGoal(t *testing.T) {
+	loc := Loc{X: 400, Y: 400}
+	known := func(b *Bot) {
+		b.setWall(0, 0)
+		b.setWall(800, 600)
+		// plenty known all round us except to the south west
+		for _, q := range []Loc{{X: 1, Y: -1}, {X: 1, Y: 1}, {X: -1, Y: -1}} {
+			for i := 1; i <= 5; i++ {
+				b.setFloor(loc.X+q.X*i*b.tileSize(), loc.Y+q.Y*i*b.tileSize())
+			}
+		}
+	}
+	tests := []struct {
+		bias string
+		want func(b *Bot) Loc
+	}{
+		{"center", func(*Bot) Loc { return Loc{X: 400, Y: 300} }},
+		{"unexplored", func(b *Bot) Loc {
+			reach := exploreRadius * b.tileSize()
+			return Loc{X: loc.X - reach, Y: loc.Y + reach}
+		}},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBot(func(cfg *Config) { cfg.ExploreBias = tt.bias })
+		if goal, ok := b.exploreGoal(loc); tt.bias == "center" && ok {
+			t.Errorf("%s: exploreGoal() = %v knowing nothing of the map", tt.bias, goal)
+		}
+		known(b)
+		if goal, ok := b.exploreGoal(loc); !ok || goal != tt.want(b) {
+			t.Errorf("%s: exploreGoal() = %v, %v, want %v", tt.bias, goal, ok, tt.want(b))
+		}
+	}
+	b, _ := newTestBot(func(cfg *Config) { cfg.ExploreBias = "none" })
+	known(b)
+	if goal, ok := b.exploreGoal(loc); ok {
+		t.Errorf("exploreGoal() = %v with no bias", goal)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/neilo40/gauntletBot
+
+go 1.21
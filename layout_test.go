@@ -0,0 +1,352 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the most coordinate pairs we put in one nearbywalls or nearbyfloors message, to stay well inside a datagram
+const layoutPairsPerMessage = 50
+
+// a generated map for tests and benchmarks.  Everything is in tiles, from 0,0 at the top left
+type layout struct {
+	w, h  int
+	walls map[Loc]bool
+	floor map[Loc]bool
+	items map[Loc]string // anything lying about other than our key, by tile
+	start Loc
+	key   Loc
+	exit  Loc
+}
+
+func newLayout(w int, h int) *layout {
+	return &layout{w: w, h: h, walls: make(map[Loc]bool), floor: make(map[Loc]bool), items: make(map[Loc]string)}
+}
+
+// a layout drawn as rows of text: # is a wall, . is floor, S where we start, K our key, E the exit,
+// a ammo and f food.  Anything else, such as a space, is a tile we're not told about
+func layoutFromRows(rows ...string) *layout {
+	l := newLayout(0, len(rows))
+	for y, row := range rows {
+		l.w = max(l.w, len(row))
+		for x, c := range row {
+			t := Loc{X: x, Y: y}
+			switch c {
+			case '#':
+				l.walls[t] = true
+				continue
+			case 'S':
+				l.start = t
+			case 'K':
+				l.key = t
+			case 'E':
+				l.exit = t
+			case 'a':
+				l.items[t] = "ammo"
+			case 'f':
+				l.items[t] = "food"
+			case '.':
+			default:
+				continue
+			}
+			l.floor[t] = true
+		}
+	}
+	return l
+}
+
+// a walled room with nothing in the way: we start in one corner, the key's in the next and the exit in the far one
+func emptyRoom(w int, h int) *layout {
+	l := newLayout(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			l.carve(Loc{X: x, Y: y}, x > 0 && y > 0 && x < w-1 && y < h-1)
+		}
+	}
+	l.start, l.key, l.exit = Loc{X: 1, Y: 1}, Loc{X: w - 2, Y: 1}, Loc{X: w - 2, Y: h - 2}
+	l.items[Loc{X: w / 2, Y: h / 2}] = "ammo"
+	return l
+}
+
+// a maze of one tile wide passages with a single route between any two places, the same for the same seed.
+// Even sizes are rounded up, as the passages sit on odd tiles
+func maze(w int, h int, seed int64) *layout {
+	w, h = w|1, h|1
+	l := newLayout(w, h)
+	l.fill()
+	rng := rand.New(rand.NewSource(seed))
+	steps := []Loc{{X: 2}, {X: -2}, {Y: 2}, {Y: -2}}
+	path := []Loc{{X: 1, Y: 1}}
+	l.carve(path[0], true)
+	for len(path) > 0 {
+		at := path[len(path)-1]
+		var next []Loc
+		for _, step := range steps {
+			t := Loc{X: at.X + step.X, Y: at.Y + step.Y}
+			if t.X > 0 && t.Y > 0 && t.X < w-1 && t.Y < h-1 && l.walls[t] {
+				next = append(next, t)
+			}
+		}
+		if len(next) == 0 {
+			path = path[:len(path)-1]
+			continue
+		}
+		t := next[rng.Intn(len(next))]
+		l.carve(Loc{X: (at.X + t.X) / 2, Y: (at.Y + t.Y) / 2}, true)
+		l.carve(t, true)
+		path = append(path, t)
+	}
+	l.scatter(rng, 6)
+	return l
+}
+
+// up to the given number of rooms, each joined to the one before by a corridor, the same for the same seed.
+// We start in the first room, the key is in the middle one and the exit in the last
+func roomsAndCorridors(w int, h int, rooms int, seed int64) *layout {
+	l := newLayout(w, h)
+	l.fill()
+	rng := rand.New(rand.NewSource(seed))
+	var centres []Loc
+	for tries := 0; tries < 100*rooms && len(centres) < rooms; tries++ {
+		rw, rh := 3+rng.Intn(6), 3+rng.Intn(6)
+		if rw > w-2 || rh > h-2 {
+			continue
+		}
+		x, y := 1+rng.Intn(w-rw-1), 1+rng.Intn(h-rh-1)
+		if !l.solid(x-1, y-1, x+rw, y+rh) {
+			continue
+		}
+		for ry := y; ry < y+rh; ry++ {
+			for rx := x; rx < x+rw; rx++ {
+				l.carve(Loc{X: rx, Y: ry}, true)
+			}
+		}
+		centre := Loc{X: x + rw/2, Y: y + rh/2}
+		if len(centres) > 0 {
+			// across, then up or down
+			from := centres[len(centres)-1]
+			for cx := min(from.X, centre.X); cx <= max(from.X, centre.X); cx++ {
+				l.carve(Loc{X: cx, Y: from.Y}, true)
+			}
+			for cy := min(from.Y, centre.Y); cy <= max(from.Y, centre.Y); cy++ {
+				l.carve(Loc{X: centre.X, Y: cy}, true)
+			}
+		}
+		centres = append(centres, centre)
+	}
+	if len(centres) == 0 {
+		panic(fmt.Sprintf("no room fits in %dx%d", w, h))
+	}
+	l.start, l.key, l.exit = centres[0], centres[len(centres)/2], centres[len(centres)-1]
+	l.scatter(rng, len(centres))
+	return l
+}
+
+// make every tile a wall
+func (l *layout) fill() {
+	for y := 0; y < l.h; y++ {
+		for x := 0; x < l.w; x++ {
+			l.walls[Loc{X: x, Y: y}] = true
+		}
+	}
+}
+
+// make a tile floor, or wall
+func (l *layout) carve(t Loc, floor bool) {
+	if floor {
+		l.floor[t] = true
+		delete(l.walls, t)
+	} else {
+		l.walls[t] = true
+		delete(l.floor, t)
+	}
+}
+
+// is every tile from x1,y1 to x2,y2 inclusive a wall?
+func (l *layout) solid(x1 int, y1 int, x2 int, y2 int) bool {
+	for y := y1; y <= y2; y++ {
+		for x := x1; x <= x2; x++ {
+			if !l.walls[Loc{X: x, Y: y}] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// the floor tiles, row by row, so anything picked from them is the same every run
+func (l *layout) floorTiles() []Loc {
+	var tiles []Loc
+	for y := 0; y < l.h; y++ {
+		for x := 0; x < l.w; x++ {
+			if t := (Loc{X: x, Y: y}); l.floor[t] {
+				tiles = append(tiles, t)
+			}
+		}
+	}
+	return tiles
+}
+
+// put the key and exit somewhere other than the start, if they haven't been placed, and drop some ammo and food about
+func (l *layout) scatter(rng *rand.Rand, n int) {
+	tiles := l.floorTiles()
+	pick := func() Loc {
+		for {
+			if t := tiles[rng.Intn(len(tiles))]; t != l.start && t != l.key && t != l.exit && l.items[t] == "" {
+				return t
+			}
+		}
+	}
+	if l.start == (Loc{}) {
+		l.start = tiles[0]
+	}
+	if l.key == (Loc{}) {
+		l.key = pick()
+	}
+	if l.exit == (Loc{}) {
+		l.exit = pick()
+	}
+	for i := 0; i < n && len(tiles) > n+3; i++ {
+		l.items[pick()] = []string{"ammo", "food"}[i%2]
+	}
+}
+
+// where in the game the middle of a tile is, for tiles of the given size
+func (l *layout) locOf(t Loc, tile int) Loc {
+	return Loc{X: t.X*tile + tile/2, Y: t.Y*tile + tile/2}
+}
+
+// the messages a server would send to describe the layout to a bot that's just joined it: where we are,
+// then the walls and floor, the items, our key (which goes by keyName) and the exit.  Feed them to
+// handleMessage, or send them from a mockServer
+func (l *layout) messages(tile int, keyName string) []string {
+	start := l.locOf(l.start, tile)
+	msgs := []string{fmt.Sprintf("playerupdate:%d,%d,100,10,False", start.X, start.Y)}
+	pairs := func(msgType string, tiles map[Loc]bool) {
+		var batch []string
+		for y := 0; y < l.h; y++ {
+			for x := 0; x < l.w; x++ {
+				if !tiles[Loc{X: x, Y: y}] {
+					continue
+				}
+				loc := l.locOf(Loc{X: x, Y: y}, tile)
+				batch = append(batch, fmt.Sprintf("%d,%d", loc.X, loc.Y))
+				if len(batch) == layoutPairsPerMessage {
+					msgs = append(msgs, msgType+":"+strings.Join(batch, ","))
+					batch = batch[:0]
+				}
+			}
+		}
+		if len(batch) > 0 {
+			msgs = append(msgs, msgType+":"+strings.Join(batch, ","))
+		}
+	}
+	pairs("nearbywalls", l.walls)
+	pairs("nearbyfloors", l.floor)
+	for _, t := range l.floorTiles() {
+		if item, ok := l.items[t]; ok {
+			loc := l.locOf(t, tile)
+			msgs = append(msgs, fmt.Sprintf("nearbyitem:%s,%d,%d", item, loc.X, loc.Y))
+		}
+	}
+	key, exit := l.locOf(l.key, tile), l.locOf(l.exit, tile)
+	msgs = append(msgs, fmt.Sprintf("nearbyitem:%s,%d,%d", keyName, key.X, key.Y))
+	msgs = append(msgs, fmt.Sprintf("exit:%d,%d", exit.X, exit.Y))
+	return msgs
+}
+
+// a bot that's been told everything about a layout, and the clock it keeps time by
+func loadLayout(tb testing.TB, l *layout, configure func(*Config)) (*Bot, *FakeClock) {
+	tb.Helper()
+	b, clock := newTestBot(configure)
+	for _, msg := range l.messages(b.tileSize(), b.myKeyName()) {
+		b.handleMessage(msg)
+	}
+	return b, clock
+}
+
+// two rooms joined by a door, with the key at the back of the second and the exit in the first
+var layoutTwoRooms = []string{
+	"###########",
+	"#S..#....K#",
+	"#...#.....#",
+	"#.a.......#",
+	"#...#..f..#",
+	"#..E#.....#",
+	"###########",
+}
+
+// a corridor with dead ends off it, the key down one of them
+var layoutDeadEnds = []string{
+	"#############",
+	"#S.........E#",
+	"#.###.###.###",
+	"#.# #.# #.#  ",
+	"### #K# #a#  ",
+	"    ### ###  ",
+}
+
+func testLayouts() map[string]*layout {
+	return map[string]*layout{
+		"two rooms":           layoutFromRows(layoutTwoRooms...),
+		"dead ends":           layoutFromRows(layoutDeadEnds...),
+		"empty room":          emptyRoom(12, 9),
+		"maze":                maze(21, 15, 1),
+		"rooms and corridors": roomsAndCorridors(40, 30, 6, 1),
+	}
+}
+
+func TestLayouts(t *testing.T) {
+	for name, l := range testLayouts() {
+		t.Run(name, func(t *testing.T) {
+			b, _ := loadLayout(t, l, nil)
+			s := b.Snapshot()
+			if s.Walls != len(l.walls) || s.Floors != len(l.floor) {
+				t.Errorf("know of %d walls and %d floors, want %d and %d", s.Walls, s.Floors, len(l.walls), len(l.floor))
+			}
+			tile := b.tileSize()
+			if s.Player.Loc != l.locOf(l.start, tile) {
+				t.Errorf("at %v, want %v", s.Player.Loc, l.locOf(l.start, tile))
+			}
+			if s.Player.MyKey == nil || s.Player.Exit == nil {
+				t.Fatalf("key %v or exit %v not found", s.Player.MyKey, s.Player.Exit)
+			}
+			if _, ok := b.findPath(s.Player.Loc, *s.Player.MyKey, false); !ok {
+				t.Error("no path to the key")
+			}
+			if _, ok := b.findPath(*s.Player.MyKey, *s.Player.Exit, false); !ok {
+				t.Error("no path from the key to the exit")
+			}
+		})
+	}
+}
+
+func TestLayoutsRepeat(t *testing.T) {
+	a, b := strings.Join(maze(31, 31, 7).messages(8, "redkey"), "\n"), strings.Join(maze(31, 31, 7).messages(8, "redkey"), "\n")
+	if a != b {
+		t.Error("the same seed made two different mazes")
+	}
+	if a == strings.Join(maze(31, 31, 8).messages(8, "redkey"), "\n") {
+		t.Error("different seeds made the same maze")
+	}
+}
+
+func TestLayoutOverTheWire(t *testing.T) {
+	l := layoutFromRows(layoutTwoRooms...)
+	s := newMockServer(t)
+	b := runTestBot(t, s, nil)
+	s.expect("requestjoin:", nil)
+	s.send("playerjoined:warrior,1")
+	s.send(l.messages(DefaultConfig().TileSize, "redkey")...)
+	deadline := time.Now().Add(2 * time.Second)
+	for b.Snapshot().Player.Exit == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	snap := b.Snapshot()
+	if snap.Walls != len(l.walls) || snap.Player.MyKey == nil || snap.Player.Exit == nil {
+		t.Errorf("learnt %d of %d walls, key %v and exit %v", snap.Walls, len(l.walls), snap.Player.MyKey, snap.Player.Exit)
+	}
+}
@@ -0,0 +1,49 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// how much we log, from everything down to only things going wrong.  Lines below the level are dropped
+const (
+	levelDebug int32 = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var levelNames = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+var logLevel atomic.Int32 // shared by every bot in the process
+
+func init() {
+	logLevel.Store(levelInfo)
+}
+
+// SetLogLevel sets which messages get logged: debug, info, warn or error and above
+func SetLogLevel(name string) error {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			logLevel.Store(int32(level))
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown log level %q, want debug, info, warn or error", name)
+}
+
+func logf(level int32, format string, args ...interface{}) {
+	if level < logLevel.Load() {
+		return
+	}
+	log.Printf(levelNames[level]+" "+format+"\n", args...)
+}
+
+func debugf(format string, args ...interface{}) { logf(levelDebug, format, args...) }
+
+// Infof, Warnf and Errorf log at their level, so programs running bots can log alongside them
+func Infof(format string, args ...interface{})  { logf(levelInfo, format, args...) }
+func Warnf(format string, args ...interface{})  { logf(levelWarn, format, args...) }
+func Errorf(format string, args ...interface{}) { logf(levelError, format, args...) }
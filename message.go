@@ -0,0 +1,164 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Event is one thing the server has told us, parsed out of a datagram but not yet applied to our state
+type Event interface {
+	event()
+}
+
+// PlayerJoined confirms our join, with the name and id the server knows us by
+type PlayerJoined struct {
+	Name   string
+	ID     string
+	Loc    Loc
+	Placed bool // whether the server told us where we are, which it may not if we join mid-game
+}
+
+// PlayerUpdate is our own position and status
+type PlayerUpdate struct {
+	Loc    Loc
+	Health int
+	Ammo   int
+	HasKey bool
+}
+
+// ExitSeen is where the exit is
+type ExitSeen struct {
+	Loc Loc
+}
+
+// NearbyItem is an item in view, which may be a key
+type NearbyItem struct {
+	Type string
+	Loc  Loc
+}
+
+// NearbyPlayer is another player in view, friend or foe.  Facing is empty and Health -1 if the server didn't say
+type NearbyPlayer struct {
+	Name   string
+	Class  string
+	Loc    Loc
+	Facing string
+	Health int
+}
+
+// NearbyWalls is the wall tiles in view
+type NearbyWalls struct {
+	Walls []Loc
+}
+
+// NearbyFloors is the floor tiles in view
+type NearbyFloors struct {
+	Floors []Loc
+}
+
+// RoundStart and RoundEnd are the server's (configurable) round messages
+type RoundStart struct{}
+type RoundEnd struct{}
+
+// Unrecognised is any message we don't act on
+type Unrecognised struct {
+	Text string
+}
+
+func (PlayerJoined) event() {}
+func (PlayerUpdate) event() {}
+func (ExitSeen) event()     {}
+func (NearbyItem) event()   {}
+func (NearbyPlayer) event() {}
+func (NearbyWalls) event()  {}
+func (NearbyFloors) event() {}
+func (RoundStart) event()   {}
+func (RoundEnd) event()     {}
+func (Unrecognised) event() {}
+
+// parse one datagram into the event it describes.  Messages with missing or nonsense parameters are an error
+func (b *Bot) parseMessage(raw []byte) (Event, error) {
+	// strip the NUL padding and any stray whitespace from the whole datagram before we split it up
+	msgString := strings.Trim(string(raw), "\x00 \t\r\n")
+	msgParts := strings.SplitN(msgString, ":", 2)
+	msgType := msgParts[0]
+	paramString := ""
+	if len(msgParts) > 1 {
+		paramString = msgParts[1]
+	}
+	msgParams := splitParams(paramString)
+	if want, ok := minParams[msgType]; ok && len(msgParams) < want {
+		return nil, fmt.Errorf("ignoring %s with %d parameters, expected at least %d: %s", msgType, len(msgParams), want, paramString)
+	}
+	switch msgType {
+	case "playerjoined":
+		// usually name,id,x,y, but don't count on the id or the position being there
+		joined := PlayerJoined{Name: msgParams[0]}
+		if loc, ok := parseLoc(msgParams, 2); ok {
+			joined.ID = msgParams[1]
+			joined.Loc, joined.Placed = loc, true
+		} else if loc, ok := parseLoc(msgParams, 1); ok {
+			joined.Loc, joined.Placed = loc, true
+		} else if len(msgParams) > 1 {
+			joined.ID = msgParams[1]
+		}
+		if joined.Name == "" {
+			return nil, fmt.Errorf("ignoring playerjoined without a name: %s", paramString)
+		}
+		return joined, nil
+	case "playerupdate":
+		x, okX := parseCoord(msgParams[0])
+		y, okY := parseCoord(msgParams[1])
+		if !okX || !okY {
+			return nil, fmt.Errorf("ignoring playerupdate with a nonsense position: %s", paramString)
+		}
+		health, errHealth := strconv.Atoi(msgParams[2])
+		ammo, errAmmo := strconv.Atoi(msgParams[3])
+		if errHealth != nil || errAmmo != nil {
+			return nil, fmt.Errorf("ignoring playerupdate with bad health or ammo: %s", paramString)
+		}
+		hasKey := strings.HasPrefix(msgParams[4], "True")
+		return PlayerUpdate{Loc: Loc{X: x, Y: y}, Health: health, Ammo: ammo, HasKey: hasKey}, nil
+	case "exit":
+		x, okX := parseCoord(msgParams[0])
+		y, okY := parseCoord(msgParams[1])
+		if !okX || !okY {
+			return nil, fmt.Errorf("ignoring exit with a nonsense position: %s", paramString)
+		}
+		return ExitSeen{Loc: Loc{X: x, Y: y}}, nil
+	case "nearbyitem":
+		x, okX := parseCoord(msgParams[1])
+		y, okY := parseCoord(msgParams[2])
+		if !okX || !okY {
+			return nil, fmt.Errorf("ignoring nearbyitem with a nonsense position: %s", paramString)
+		}
+		return NearbyItem{Type: msgParams[0], Loc: Loc{X: x, Y: y}}, nil
+	case "nearbyplayer":
+		x, okX := parseCoord(msgParams[2])
+		y, okY := parseCoord(msgParams[3])
+		if !okX || !okY {
+			return nil, fmt.Errorf("ignoring nearbyplayer with a nonsense position: %s", paramString)
+		}
+		// name,class,x,y and then, from servers that send them, facing and health.  Either may be missing,
+		// so each is recognised by its form: a compass direction or a whole number
+		player := NearbyPlayer{Name: msgParams[0], Class: msgParams[1], Loc: Loc{X: x, Y: y}, Health: -1}
+		for _, param := range msgParams[4:] {
+			if compassIndex(param) >= 0 && player.Facing == "" {
+				player.Facing = param
+			} else if health, err := strconv.Atoi(param); err == nil && player.Health < 0 && health >= 0 {
+				player.Health = health
+			}
+		}
+		return player, nil
+	case "nearbywalls":
+		return NearbyWalls{Walls: coordPairs(msgType, paramString)}, nil
+	case "nearbyfloors":
+		return NearbyFloors{Floors: coordPairs(msgType, paramString)}, nil
+	case b.cfg.RoundEndMsg:
+		return RoundEnd{}, nil
+	case b.cfg.RoundStartMsg:
+		return RoundStart{}, nil
+	}
+	return Unrecognised{Text: msgString}, nil
+}
@@ -0,0 +1,86 @@
+package gauntletbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Event
+		wantErr bool
+	}{
+		{raw: "playerjoined:warrior,1,10,20", want: PlayerJoined{Name: "warrior", ID: "1", Loc: Loc{X: 10, Y: 20}, Placed: true}},
+		{raw: "playerjoined:warrior,10,20", want: PlayerJoined{Name: "warrior", Loc: Loc{X: 10, Y: 20}, Placed: true}},
+		{raw: "playerjoined:warrior,1", want: PlayerJoined{Name: "warrior", ID: "1"}},
+		{raw: "playerjoined:warrior", want: PlayerJoined{Name: "warrior"}},
+		{raw: "playerjoined:", wantErr: true},
+		{raw: "playerupdate:10,20,5,3,False", want: PlayerUpdate{Loc: Loc{X: 10, Y: 20}, Health: 5, Ammo: 3}},
+		{raw: "playerupdate:10.5,20.9,5,3,True", want: PlayerUpdate{Loc: Loc{X: 10, Y: 20}, Health: 5, Ammo: 3, HasKey: true}},
+		{raw: "playerupdate:10,20,5,3", wantErr: true},
+		{raw: "playerupdate:ten,20,5,3,False", wantErr: true},
+		{raw: "playerupdate:NaN,20,5,3,False", wantErr: true},
+		{raw: "playerupdate:10,20,lots,3,False", wantErr: true},
+		{raw: "exit:40,60", want: ExitSeen{Loc: Loc{X: 40, Y: 60}}},
+		{raw: "exit:40", wantErr: true},
+		{raw: "exit:40,south", wantErr: true},
+		{raw: "exit:40,1e9", wantErr: true},
+		{raw: "exit:40.5,60.9", want: ExitSeen{Loc: Loc{X: 40, Y: 60}}},
+		{raw: "nearbyitem:redkey,8,16", want: NearbyItem{Type: "redkey", Loc: Loc{X: 8, Y: 16}}},
+		{raw: "nearbyitem:redkey,8", wantErr: true},
+		{raw: "nearbyitem:redkey,8,x", wantErr: true},
+		{raw: "nearbyitem:ammo,Inf,16", wantErr: true},
+		{raw: "nearbyitem:ammo,-2000000,16", wantErr: true},
+		{raw: "nearbyplayer:orc,grunt,8,16", want: NearbyPlayer{Name: "orc", Class: "grunt", Loc: Loc{X: 8, Y: 16}, Health: -1}},
+		{raw: "nearbyplayer:orc,grunt,8,16,sw,7", want: NearbyPlayer{Name: "orc", Class: "grunt", Loc: Loc{X: 8, Y: 16}, Facing: "sw", Health: 7}},
+		{raw: "nearbyplayer:orc,grunt,8,16,7", want: NearbyPlayer{Name: "orc", Class: "grunt", Loc: Loc{X: 8, Y: 16}, Health: 7}},
+		{raw: "nearbyplayer:orc,grunt,8", wantErr: true},
+		{raw: "nearbywalls:0,0,8,0", want: NearbyWalls{Walls: []Loc{{X: 0, Y: 0}, {X: 8, Y: 0}}}},
+		{raw: "nearbywalls:0,0,8", want: NearbyWalls{Walls: []Loc{{X: 0, Y: 0}}}},
+		{raw: "nearbywalls:0,0,x,0", want: NearbyWalls{Walls: []Loc{{X: 0, Y: 0}}}},
+		{raw: "nearbywalls:0,0,1e12,0,NaN,8", want: NearbyWalls{Walls: []Loc{{X: 0, Y: 0}}}},
+		{raw: "nearbyfloors:8.5,8,16,Inf", want: NearbyFloors{Floors: []Loc{{X: 8, Y: 8}}}},
+		{raw: "nearbywalls:", want: NearbyWalls{}},
+		{raw: "nearbyfloors:8,8", want: NearbyFloors{Floors: []Loc{{X: 8, Y: 8}}}},
+		{raw: "roundstart", want: RoundStart{}},
+		{raw: "roundend", want: RoundEnd{}},
+		{raw: "scores:warrior,10", want: Unrecognised{Text: "scores:warrior,10"}},
+		{raw: "", want: Unrecognised{}},
+		// padded with NULs, as a whole and field by field
+		{raw: "exit:40,60\x00\x00\x00", want: ExitSeen{Loc: Loc{X: 40, Y: 60}}},
+		{raw: "\x00playerupdate:10\x00\x00,20\x00,5,3,False\x00", want: PlayerUpdate{Loc: Loc{X: 10, Y: 20}, Health: 5, Ammo: 3}},
+		{raw: "nearbyitem:redkey\x00,8 ,16\r\n", want: NearbyItem{Type: "redkey", Loc: Loc{X: 8, Y: 16}}},
+	}
+	b, _ := newTestBot(nil)
+	for _, tt := range tests {
+		got, err := b.parseMessage([]byte(tt.raw))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseMessage(%q) = %#v, want an error", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseMessage(%q): %v", tt.raw, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseMessage(%q) = %#v, want %#v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseMessageRoundNames(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) {
+		cfg.RoundStartMsg = "gamebegin"
+		cfg.RoundEndMsg = "gameover"
+	})
+	for raw, want := range map[string]Event{
+		"gamebegin":  RoundStart{},
+		"gameover":   RoundEnd{},
+		"roundstart": Unrecognised{Text: "roundstart"},
+	} {
+		if got, err := b.parseMessage([]byte(raw)); err != nil || got != want {
+			t.Errorf("parseMessage(%q) = %#v, %v, want %#v", raw, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package gauntletbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// how soon before an enemy drops out of sight we must have fired at it to count it as a kill
+const killWindow = time.Second
+
+// counters for -metricsaddr.  The read loop, write loop and watchdog update them as they go and
+// the HTTP server reads them, so everything is under mu
+type metrics struct {
+	mu           sync.Mutex
+	clock        Clock
+	messages     map[string]int64     // datagrams received, by message type
+	parseErrors  int64                // datagrams we couldn't make sense of
+	reconnects   int64                // times the watchdog has reconnected us
+	kills        int64                // enemies that vanished just after we fired at them
+	sightChecks  int64                // line of sight questions asked
+	sightHits    int64                // of those, how many the cache answered
+	wallTests    int64                // walls tested against a line for the rest
+	shotAt       map[string]time.Time // when we last fired at each enemy
+	exitDistance float64              // how far we are from the exit, if exitKnown
+	exitKnown    bool
+}
+
+func newMetrics(clock Clock) *metrics {
+	return &metrics{clock: clock, messages: make(map[string]int64), shotAt: make(map[string]time.Time)}
+}
+
+func (m *metrics) countMessage(msgType string, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages[msgType]++
+	if !ok {
+		m.parseErrors++
+	}
+}
+
+func (m *metrics) countReconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconnects++
+}
+
+// a line of sight check, answered from the cache or by testing the given number of walls
+func (m *metrics) countSight(cached bool, walls int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sightChecks++
+	if cached {
+		m.sightHits++
+	}
+	m.wallTests += int64(walls)
+}
+
+func (m *metrics) noteShot(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shotAt[name] = m.clock.Now()
+}
+
+// an enemy has dropped out of our list, having last been seen at the given time.  If we were
+// shooting at them just before, chances are they're dead
+func (m *metrics) noteGone(name string, seen time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	shot, ok := m.shotAt[name]
+	if !ok {
+		return
+	}
+	delete(m.shotAt, name)
+	if !shot.Before(seen.Add(-killWindow)) {
+		m.kills++
+	}
+}
+
+func (m *metrics) setExitDistance(distance float64, known bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exitDistance = distance
+	m.exitKnown = known
+}
+
+// write the metrics in the Prometheus text format
+func (b *Bot) writeMetrics(w io.Writer) {
+	p := b.snapshotPlayer()
+	m := b.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	types := make([]string, 0, len(m.messages))
+	for msgType := range m.messages {
+		types = append(types, msgType)
+	}
+	sort.Strings(types)
+	fmt.Fprintln(w, "# HELP gauntletbot_messages_total Datagrams received from the server, by message type.")
+	fmt.Fprintln(w, "# TYPE gauntletbot_messages_total counter")
+	for _, msgType := range types {
+		fmt.Fprintf(w, "gauntletbot_messages_total{type=%q} %d\n", msgType, m.messages[msgType])
+	}
+	writeMetric(w, "parse_errors_total", "counter", "Datagrams we couldn't make sense of.", float64(m.parseErrors))
+	writeMetric(w, "reconnects_total", "counter", "Times we've reconnected after the server went quiet.", float64(m.reconnects))
+	writeMetric(w, "kills_total", "counter", "Enemies that vanished just after we fired at them.", float64(m.kills))
+	writeMetric(w, "sight_checks_total", "counter", "Line of sight checks.", float64(m.sightChecks))
+	writeMetric(w, "sight_cache_hits_total", "counter", "Line of sight checks answered from the cache.", float64(m.sightHits))
+	writeMetric(w, "sight_wall_tests_total", "counter", "Walls tested against a line of sight.", float64(m.wallTests))
+	writeMetric(w, "health", "gauge", "Our health.", float64(p.Health))
+	writeMetric(w, "ammo", "gauge", "Shots we have left.", float64(p.Ammo))
+	hasKey := 0.0
+	if p.HasKey {
+		hasKey = 1
+	}
+	writeMetric(w, "has_key", "gauge", "1 if we have our key.", hasKey)
+	if m.exitKnown {
+		writeMetric(w, "exit_distance", "gauge", "How far we are from the exit, once we know where it is.", m.exitDistance)
+	}
+}
+
+func writeMetric(w io.Writer, name string, kind string, help string, value float64) {
+	fmt.Fprintf(w, "# HELP gauntletbot_%s %s\n# TYPE gauntletbot_%s %s\ngauntletbot_%s %g\n", name, help, name, kind, name, value)
+}
+
+// the monitor API: /metrics, and the controls for steering the bot from outside
+func (b *Bot) monitorHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		b.writeMetrics(w)
+	})
+	mux.HandleFunc("/override", b.handleOverride)
+	mux.HandleFunc("/trace", b.handleTrace)
+	return mux
+}
+
+// serve the monitor API on addr until the context is cancelled
+func (b *Bot) serveMetrics(ctx context.Context, addr string) {
+	server := &http.Server{Addr: addr, Handler: b.monitorHandler()}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	Infof("Serving metrics on %s/metrics, and controls on /override and /trace", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		Errorf("Metrics server stopped: %v", err)
+	}
+}
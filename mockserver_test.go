@@ -0,0 +1,127 @@
+package gauntletbot
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockServer stands in for the game server on a loopback UDP socket: it sends whatever the test
+// scripts to the last client that wrote to it, and collects everything the client sends
+type mockServer struct {
+	t        testing.TB
+	conn     *net.UDPConn
+	received chan string
+
+	mu     sync.Mutex
+	client *net.UDPAddr
+}
+
+func newMockServer(t testing.TB) *mockServer {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &mockServer{t: t, conn: conn, received: make(chan string, 1024)}
+	go s.listen()
+	t.Cleanup(func() { conn.Close() })
+	return s
+}
+
+func (s *mockServer) listen() {
+	buf := make([]byte, readBufferSize)
+	for {
+		n, from, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.client = from
+		s.mu.Unlock()
+		select {
+		case s.received <- string(buf[:n]):
+		default:
+			// nobody's checking, the test only cares about recent commands
+		}
+	}
+}
+
+func (s *mockServer) port() int {
+	return s.conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// send each message to the client as a datagram of its own
+func (s *mockServer) send(messages ...string) {
+	s.t.Helper()
+	s.mu.Lock()
+	client := s.client
+	s.mu.Unlock()
+	if client == nil {
+		s.t.Fatal("nothing to send to, the bot hasn't sent us anything yet")
+	}
+	for _, msg := range messages {
+		if _, err := s.conn.WriteToUDP([]byte(msg), client); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+}
+
+// wait for the bot to send a command with the given prefix that satisfies ok, skipping any others.
+// Returns the command without its prefix
+func (s *mockServer) expect(prefix string, ok func(params string) bool) string {
+	s.t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-s.received:
+			if params, found := strings.CutPrefix(msg, prefix); found && (ok == nil || ok(params)) {
+				return params
+			}
+		case <-timeout:
+			s.t.Fatalf("timed out waiting for the bot to send %s", prefix)
+		}
+	}
+}
+
+// start a bot playing against the mock server, stopped when the test ends
+func runTestBot(t testing.TB, s *mockServer, configure func(*Config)) *Bot {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Host = "127.0.0.1"
+	cfg.Port = s.port()
+	cfg.Name = "warrior"
+	cfg.TickMs = minTickMs
+	cfg.ReconnectTimeout = 0
+	if configure != nil {
+		configure(&cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	b := NewBot(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := b.Run(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return b
+}
+
+// the x,y of a moveto
+func movetoLoc(params string) Loc {
+	var loc Loc
+	fmt.Sscanf(params, "%d,%d", &loc.X, &loc.Y)
+	return loc
+}
@@ -0,0 +1,150 @@
+package gauntletbot
+
+import (
+	"math"
+	"time"
+)
+
+// remember when we asked to move, so the next playerupdate that shows us moving tells us the latency
+func (b *Bot) noteMoveSent(from Loc) {
+	b.motionMutex.Lock()
+	defer b.motionMutex.Unlock()
+	if b.state.Motion.moveSent.IsZero() {
+		b.state.Motion.moveSent = b.clock.Now()
+		b.state.Motion.moveFrom = from
+	}
+}
+
+// update our latency and velocity estimates from a new playerupdate position
+func (b *Bot) trackMotion(loc Loc) {
+	b.motionMutex.Lock()
+	defer b.motionMutex.Unlock()
+	m := &b.state.Motion
+	now := b.clock.Now()
+	if !m.moveSent.IsZero() && loc != m.moveFrom {
+		sample := now.Sub(m.moveSent)
+		if m.Latency == 0 {
+			m.Latency = sample
+		} else {
+			// smooth out the jitter
+			m.Latency = (m.Latency*7 + sample) / 8
+		}
+		m.moveSent = time.Time{}
+	}
+	if !m.lastSeen.IsZero() {
+		if elapsed := now.Sub(m.lastSeen).Seconds(); elapsed > 0 {
+			m.VX = float64(loc.X-m.lastLoc.X) / elapsed
+			m.VY = float64(loc.Y-m.lastLoc.Y) / elapsed
+		}
+	}
+	m.lastLoc = loc
+	m.lastSeen = now
+}
+
+// where we'll be by the time the server acts on what we send now, if prediction is on
+func (b *Bot) selfLoc() Loc {
+	loc := b.snapshotPlayer().Loc
+	if !b.cfg.PredictMotion {
+		return loc
+	}
+	b.motionMutex.Lock()
+	defer b.motionMutex.Unlock()
+	ahead := b.state.Motion.Latency.Seconds()
+	return Loc{X: loc.X + int(math.Round(b.state.Motion.VX*ahead)), Y: loc.Y + int(math.Round(b.state.Motion.VY*ahead))}
+}
+
+// a bounded ring of where one enemy has been seen, oldest first once it wraps
+type sightingHistory struct {
+	seen []Item
+	next int
+}
+
+// remember where an enemy was seen, skipping repeats of the last sighting that arrive in the same datagram burst
+func (b *Bot) recordSighting(name string, loc Loc) {
+	if b.cfg.EnemyHistory < 2 {
+		return
+	}
+	b.motionMutex.Lock()
+	defer b.motionMutex.Unlock()
+	h, ok := b.sightings[name]
+	if !ok {
+		h = &sightingHistory{}
+		b.sightings[name] = h
+	}
+	now := b.clock.Now()
+	if n := len(h.seen); n > 0 {
+		last := h.seen[(h.next+n-1)%n]
+		if last.Loc == loc && now.Sub(last.Seen) < sightingDedupWindow {
+			return
+		}
+	}
+	sighting := Item{Type: name, Loc: loc, Seen: now}
+	if len(h.seen) < b.cfg.EnemyHistory {
+		h.seen = append(h.seen, sighting)
+		return
+	}
+	h.seen[h.next] = sighting
+	h.next = (h.next + 1) % len(h.seen)
+}
+
+// an enemy's velocity in units per second, from a least squares fit over its recent sightings,
+// which copes with jittery positions far better than the difference between the last two
+func (b *Bot) enemyVelocity(name string) (float64, float64, bool) {
+	b.motionMutex.Lock()
+	defer b.motionMutex.Unlock()
+	h, ok := b.sightings[name]
+	if !ok {
+		return 0, 0, false
+	}
+	return h.velocity()
+}
+
+// the least squares fit behind enemyVelocity.  Must be called with motionMutex held
+func (h *sightingHistory) velocity() (float64, float64, bool) {
+	if len(h.seen) < 2 {
+		return 0, 0, false
+	}
+	origin := h.seen[0].Seen
+	var meanT, meanX, meanY float64
+	for _, s := range h.seen {
+		meanT += s.Seen.Sub(origin).Seconds()
+		meanX += float64(s.Loc.X)
+		meanY += float64(s.Loc.Y)
+	}
+	n := float64(len(h.seen))
+	meanT, meanX, meanY = meanT/n, meanX/n, meanY/n
+	var varT, covX, covY float64
+	for _, s := range h.seen {
+		dt := s.Seen.Sub(origin).Seconds() - meanT
+		varT += dt * dt
+		covX += dt * (float64(s.Loc.X) - meanX)
+		covY += dt * (float64(s.Loc.Y) - meanY)
+	}
+	if varT == 0 {
+		return 0, 0, false
+	}
+	return covX / varT, covY / varT, true
+}
+
+// where to aim at an enemy we can see at loc: where it'll have got to by the time the server
+// acts on our shot, carrying on from the latest sighting at the velocity fitted to its recent ones
+func (b *Bot) leadTarget(name string, loc Loc) Loc {
+	b.motionMutex.Lock()
+	defer b.motionMutex.Unlock()
+	h, ok := b.sightings[name]
+	if !ok || len(h.seen) == 0 {
+		return loc
+	}
+	n := len(h.seen)
+	latest := h.seen[(h.next+n-1)%n]
+	if latest.Loc != loc {
+		// the history is behind what we're aiming at, don't guess
+		return loc
+	}
+	vx, vy, ok := h.velocity()
+	if !ok {
+		return loc
+	}
+	ahead := b.clock.Now().Add(b.state.Motion.Latency).Sub(latest.Seen).Seconds()
+	return Loc{X: loc.X + int(math.Round(vx*ahead)), Y: loc.Y + int(math.Round(vy*ahead))}
+}
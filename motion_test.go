@@ -0,0 +1,76 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestEnemyVelocityNoisy(t *testing.T) {
+	b, clock := newTestBot(func(cfg *Config) { cfg.EnemyHistory = 8 })
+	const vx, vy = 50.0, -20.0
+	rng := rand.New(rand.NewSource(1))
+	start := clock.Now()
+	for i := 0; i < 12; i++ {
+		elapsed := clock.Now().Sub(start).Seconds()
+		// true position, give or take a couple of units of jitter
+		x := 100 + int(math.Round(vx*elapsed)) + rng.Intn(5) - 2
+		y := 300 + int(math.Round(vy*elapsed)) + rng.Intn(5) - 2
+		b.handleMessage(fmt.Sprintf("nearbyplayer:orc,grunt,%d,%d", x, y))
+		clock.Advance(100 * time.Millisecond)
+	}
+	gotX, gotY, ok := b.enemyVelocity("orc")
+	if !ok {
+		t.Fatal("no velocity from 12 sightings")
+	}
+	if math.Abs(gotX-vx) > 5 || math.Abs(gotY-vy) > 5 {
+		t.Errorf("velocity (%.1f,%.1f), want within 5 of (%.0f,%.0f)", gotX, gotY, vx, vy)
+	}
+}
+
+func TestLeadTarget(t *testing.T) {
+	b, clock := newTestBot(nil)
+	b.state.Motion.Latency = 200 * time.Millisecond
+	// heading east at 40 units a second and south at 20
+	var last Loc
+	for i := 0; i < 5; i++ {
+		last = Loc{X: 100 + 4*i, Y: 200 + 2*i}
+		b.handleMessage(fmt.Sprintf("nearbyplayer:orc,grunt,%d,%d", last.X, last.Y))
+		clock.Advance(100 * time.Millisecond)
+	}
+	// 100ms since the last sighting and 200ms for the shot to land: 0.3s of travel on
+	want := Loc{X: last.X + 12, Y: last.Y + 6}
+	if got := b.leadTarget("orc", last); got != want {
+		t.Errorf("aimed at %v, want %v", got, want)
+	}
+	// aiming somewhere the history hasn't caught up with is left alone
+	if got := b.leadTarget("orc", Loc{X: 500, Y: 500}); got != (Loc{X: 500, Y: 500}) {
+		t.Errorf("led a target we haven't tracked to %v", got)
+	}
+	if got := b.leadTarget("elf", last); got != last {
+		t.Errorf("led an enemy we've never sighted to %v", got)
+	}
+}
+
+func TestPredictMotion(t *testing.T) {
+	for _, predict := range []bool{false, true} {
+		b, clock := newTestBot(func(cfg *Config) { cfg.PredictMotion = predict })
+		b.handleMessage("playerupdate:100,100,10,10,False")
+		b.noteMoveSent(Loc{X: 100, Y: 100})
+		// the server takes 200ms to show us moving, at 50 a second
+		clock.Advance(200 * time.Millisecond)
+		b.handleMessage("playerupdate:110,95,10,10,False")
+		if latency := b.state.Motion.Latency; latency != 200*time.Millisecond {
+			t.Fatalf("measured %s of latency, want 200ms", latency)
+		}
+		want := Loc{X: 110, Y: 95}
+		if predict {
+			want = Loc{X: 120, Y: 90}
+		}
+		if got := b.selfLoc(); got != want {
+			t.Errorf("predicting %v: selfLoc() = %v, want %v", predict, got, want)
+		}
+	}
+}
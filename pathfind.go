@@ -0,0 +1,268 @@
+package gauntletbot
+
+import (
+	"container/heap"
+	"math"
+)
+
+const maxPathNodes = 20000 // give up rather than search forever through open, unknown space
+const maxLookahead = 8     // how many waypoints ahead to check for one we can cut straight to
+
+const diagonalByMetric = -1 // a DiagonalCost that leaves the cost of a diagonal step to the DistanceMetric
+
+// a snapshot of what we know, by tile, for the pathfinder to search over
+type grid struct {
+	floor map[Loc]Loc // tile -> a position the server reported as floor in it
+	walls map[Loc]bool
+	tile  int
+	lo    Loc // tile bounds we're willing to search within
+	hi    Loc
+
+	diagonal float64 // cost of a diagonal step relative to a straight one, 0 for no diagonal steps
+}
+
+// findPath searches the known map for a route from start to goal, returning waypoints in game coordinates
+// (excluding start, ending at goal).  With allowUnknown false we only walk on tiles we've seen floor in,
+// so we never commit to crossing a gap that might be a pit; with it true anything not known to be wall will do
+func (b *Bot) findPath(start Loc, goal Loc, allowUnknown bool) ([]Loc, bool) {
+	return b.searchPath(b.snapshotGrid(start, goal), start, goal, allowUnknown)
+}
+
+// aStar finds a route from start to goal over the walls and floors we know, preferring tiles we've
+// seen floor in and only risking unknown ones if there's no other way
+func (b *Bot) aStar(start Loc, goal Loc) ([]Loc, bool) {
+	if path, ok := b.findPath(start, goal, false); ok {
+		return path, true
+	}
+	return b.findPath(start, goal, true)
+}
+
+// how far we'd have to walk to a target, along the path aStar finds, or as the crow flies if it finds none.
+// Paths are costly to find and we ask about the same items over and over within a tick, so results are kept
+// until the write loop clears pathCosts at the start of the next one
+func (b *Bot) walkingDistance(from Loc, to Loc) float64 {
+	if cost, ok := b.pathCosts[sightLine{From: from, To: to}]; ok {
+		return cost
+	}
+	cost := b.distance(from, to)
+	if path, ok := b.aStar(from, to); ok {
+		cost = 0
+		at := from
+		for _, waypoint := range path {
+			cost += b.distance(at, waypoint)
+			at = waypoint
+		}
+	}
+	b.pathCosts[sightLine{From: from, To: to}] = cost
+	return cost
+}
+
+// head for a target: straight there if nothing's in the way, otherwise to the furthest waypoint
+// on a path round whatever is that we can see from here.  False if we know of no way there
+func (b *Bot) approach(here Loc, target Loc) bool {
+	if b.canSeeItem(here, target) {
+		b.moveTo(target)
+		return true
+	}
+	path, ok := b.aStar(here, target)
+	if !ok {
+		return false
+	}
+	next := path[0]
+	for _, waypoint := range path[1:min(len(path), maxLookahead)] {
+		if !b.canSeeItem(here, waypoint) {
+			break
+		}
+		next = waypoint
+	}
+	b.tracef("no line of sight to (%d,%d), taking a %d step path via (%d,%d)", target.X, target.Y, len(path), next.X, next.Y)
+	b.moveTo(next)
+	return true
+}
+
+// is the enemy standing in the way of the only route we know to goal?  That's when there's a path over
+// known floor with them where they are, but none once their tile and the ones around it are walled off
+func (b *Bot) enemyBlocksPath(start Loc, goal Loc, enemy Loc) bool {
+	g := b.snapshotGrid(start, goal)
+	if _, ok := b.searchPath(g, start, goal, false); !ok {
+		return false
+	}
+	enemyCell := b.cellOf(enemy)
+	startCell := b.cellOf(start)
+	g.walls[enemyCell] = true
+	for _, step := range neighbours {
+		if near := (Loc{X: enemyCell.X + step.X, Y: enemyCell.Y + step.Y}); near != startCell {
+			g.walls[near] = true
+		}
+	}
+	_, ok := b.searchPath(g, start, goal, false)
+	return !ok
+}
+
+func (b *Bot) searchPath(g grid, start Loc, goal Loc, allowUnknown bool) ([]Loc, bool) {
+	startCell := b.cellOf(start)
+	goalCell := b.cellOf(goal)
+	if startCell == goalCell {
+		return []Loc{goal}, true
+	}
+
+	passable := func(cell Loc) bool {
+		if cell == goalCell {
+			return true
+		}
+		if g.walls[cell] {
+			return false
+		}
+		if _, ok := g.floor[cell]; ok {
+			return true
+		}
+		return allowUnknown && isWithinBounds(cell, g.lo, g.hi)
+	}
+
+	open := &pathQueue{}
+	heap.Push(open, &pathNode{cell: startCell, cost: 0, estimate: g.heuristic(startCell, goalCell)})
+	cameFrom := make(map[Loc]Loc)
+	best := map[Loc]float64{startCell: 0}
+	expanded := 0
+	for open.Len() > 0 && expanded < maxPathNodes {
+		current := heap.Pop(open).(*pathNode)
+		if current.cell == goalCell {
+			return g.waypoints(cameFrom, startCell, goalCell, goal), true
+		}
+		if current.cost > best[current.cell] {
+			continue // a cheaper route here was already expanded
+		}
+		expanded++
+		for _, step := range neighbours {
+			next := Loc{X: current.cell.X + step.X, Y: current.cell.Y + step.Y}
+			if !passable(next) {
+				continue
+			}
+			cost := 1.0
+			if step.X != 0 && step.Y != 0 {
+				if g.diagonal == 0 {
+					continue
+				}
+				// don't cut corners, both of the tiles we'd squeeze between need to be clear
+				if !passable(Loc{X: current.cell.X + step.X, Y: current.cell.Y}) || !passable(Loc{X: current.cell.X, Y: current.cell.Y + step.Y}) {
+					continue
+				}
+				cost = g.diagonal
+			}
+			cost += current.cost
+			if known, ok := best[next]; ok && known <= cost {
+				continue
+			}
+			best[next] = cost
+			cameFrom[next] = current.cell
+			heap.Push(open, &pathNode{cell: next, cost: cost, estimate: cost + g.heuristic(next, goalCell)})
+		}
+	}
+	return nil, false
+}
+
+var neighbours = []Loc{{X: 0, Y: -1}, {X: 1, Y: -1}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}, {X: -1, Y: 1}, {X: -1, Y: 0}, {X: -1, Y: -1}}
+
+// copy the walls and floors into tile sets, so the search doesn't hold the map locks
+func (b *Bot) snapshotGrid(start Loc, goal Loc) grid {
+	g := grid{floor: make(map[Loc]Loc), walls: make(map[Loc]bool), tile: b.tileSize(), diagonal: b.diagonalCost()}
+	b.wallMutex.Lock()
+	for x := range b.state.Walls {
+		for y, wall := range b.state.Walls[x] {
+			if wall {
+				g.walls[b.cellOf(Loc{X: x, Y: y})] = true
+			}
+		}
+	}
+	b.wallMutex.Unlock()
+	b.floorMutex.Lock()
+	for x := range b.state.Floor {
+		for y, floor := range b.state.Floor[x] {
+			if floor {
+				g.floor[b.cellOf(Loc{X: x, Y: y})] = Loc{X: x, Y: y}
+			}
+		}
+	}
+	b.floorMutex.Unlock()
+
+	// don't wander off into unknown space beyond the edges of what we've seen
+	lo, hi, ok := b.mapBounds()
+	if !ok {
+		lo, hi = start, start
+	}
+	lo = b.cellOf(Loc{X: min(lo.X, start.X, goal.X), Y: min(lo.Y, start.Y, goal.Y)})
+	hi = b.cellOf(Loc{X: max(hi.X, start.X, goal.X), Y: max(hi.Y, start.Y, goal.Y)})
+	g.lo = Loc{X: lo.X - 2, Y: lo.Y - 2}
+	g.hi = Loc{X: hi.X + 2, Y: hi.Y + 2}
+	return g
+}
+
+// walk the search results back from the goal, turning tiles into positions we can send in a moveto
+func (g grid) waypoints(cameFrom map[Loc]Loc, startCell Loc, goalCell Loc, goal Loc) []Loc {
+	path := []Loc{goal}
+	for cell := cameFrom[goalCell]; cell != startCell; cell = cameFrom[cell] {
+		path = append(path, g.centre(cell))
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// a position within a tile to aim for: the floor the server told us about if we have it, otherwise the middle
+func (g grid) centre(cell Loc) Loc {
+	if floor, ok := g.floor[cell]; ok {
+		return floor
+	}
+	return Loc{X: cell.X*g.tile + g.tile/2, Y: cell.Y*g.tile + g.tile/2}
+}
+
+// what a diagonal step costs the pathfinder: DiagonalCost if it's been set, otherwise what our DistanceMetric
+// says a diagonal is worth, so the heuristic below comes out as the metric itself
+func (b *Bot) diagonalCost() float64 {
+	if b.cfg.DiagonalCost != diagonalByMetric {
+		return b.cfg.DiagonalCost
+	}
+	switch b.cfg.DistanceMetric {
+	case "manhattan":
+		return 2
+	case "chebyshev":
+		return 1
+	}
+	return math.Sqrt2
+}
+
+// the cost of the cheapest possible route between two tiles.  A diagonal step is never worth more
+// than the two straight ones it replaces, so beyond a cost of 2 they stop making routes cheaper.
+// Below 1, zigzagging diagonals beat going straight, so every step could cost as little as a diagonal
+func (g grid) heuristic(a Loc, b Loc) float64 {
+	dx := math.Abs(float64(a.X - b.X))
+	dy := math.Abs(float64(a.Y - b.Y))
+	if g.diagonal == 0 {
+		return dx + dy
+	}
+	if g.diagonal < 1 {
+		return g.diagonal * math.Max(dx, dy)
+	}
+	return math.Max(dx, dy) + (math.Min(g.diagonal, 2)-1)*math.Min(dx, dy)
+}
+
+type pathNode struct {
+	cell     Loc
+	cost     float64 // cost of the best route found to here so far
+	estimate float64 // cost plus the heuristic distance still to go
+}
+
+// pathQueue is a min-heap of nodes ordered by estimate, for container/heap
+type pathQueue []*pathNode
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].estimate < q[j].estimate }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(*pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,179 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// a bot that knows of floor in every tile of a w by h rectangle with its corner at tile 0,0
+func openFloor(t *testing.T, w int, h int, configure func(*Config)) *Bot {
+	t.Helper()
+	b, _ := newTestBot(configure)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			b.setFloor(tileCentre(b, x), tileCentre(b, y))
+		}
+	}
+	return b
+}
+
+func tileCentre(b *Bot, i int) int {
+	return i*b.tileSize() + b.tileSize()/2
+}
+
+// what the pathfinder counts a path as costing, step by step from start
+func pathCost(b *Bot, start Loc, path []Loc) float64 {
+	cost := 0.0
+	at := b.cellOf(start)
+	for _, waypoint := range path {
+		next := b.cellOf(waypoint)
+		if next.X != at.X && next.Y != at.Y {
+			cost += b.diagonalCost()
+		} else {
+			cost++
+		}
+		at = next
+	}
+	return cost
+}
+
+func TestPathDiagonalCost(t *testing.T) {
+	tests := []struct {
+		name     string
+		diagonal float64
+		want     float64
+	}{
+		{"straight only", 0, 6},
+		{"natural", math.Sqrt2, 6},
+		// with cheap diagonals zigzagging along the corridor beats going straight down it
+		{"cheap diagonals", 0.25, 1.5},
+	}
+	// a cost we've been given is the cost, whatever the metric
+	for _, metric := range []string{"euclidean", "manhattan", "chebyshev"} {
+		for _, tt := range tests {
+			t.Run(metric+" "+tt.name, func(t *testing.T) {
+				b := openFloor(t, 8, 3, func(cfg *Config) {
+					cfg.DiagonalCost = tt.diagonal
+					cfg.DistanceMetric = metric
+				})
+				if got := b.diagonalCost(); got != tt.diagonal {
+					t.Errorf("diagonals cost %g, want the %g configured", got, tt.diagonal)
+				}
+				start := Loc{X: tileCentre(b, 0), Y: tileCentre(b, 1)}
+				goal := Loc{X: tileCentre(b, 6), Y: tileCentre(b, 1)}
+				path, ok := b.findPath(start, goal, false)
+				if !ok {
+					t.Fatal("no path down an open corridor")
+				}
+				if got := pathCost(b, start, path); math.Abs(got-tt.want) > 1e-9 {
+					t.Errorf("path %v costs %g, want %g", path, got, tt.want)
+				}
+				if tt.diagonal == 0 {
+					for _, waypoint := range path {
+						if b.cellOf(waypoint).Y != 1 {
+							t.Errorf("left the row at %v with diagonals off", waypoint)
+						}
+					}
+				}
+			})
+		}
+	}
+	// and left unset, it's what the metric says a diagonal is worth
+	for metric, want := range map[string]float64{"euclidean": math.Sqrt2, "manhattan": 2, "chebyshev": 1} {
+		b, _ := newTestBot(func(cfg *Config) { cfg.DistanceMetric = metric })
+		if got := b.diagonalCost(); got != want {
+			t.Errorf("%s: diagonals cost %g by default, want %g", metric, got, want)
+		}
+	}
+}
+
+func TestPathAroundCorner(t *testing.T) {
+	b, _ := newTestBot(nil)
+	// a corridor along the top row that turns down the sixth column
+	for i := 0; i < 6; i++ {
+		b.setFloor(tileCentre(b, i), tileCentre(b, 0))
+		b.setFloor(tileCentre(b, 5), tileCentre(b, i))
+	}
+	start := Loc{X: tileCentre(b, 0), Y: tileCentre(b, 0)}
+	goal := Loc{X: tileCentre(b, 5), Y: tileCentre(b, 5)}
+	path, ok := b.findPath(start, goal, false)
+	if !ok {
+		t.Fatal("no path round the corner")
+	}
+	if len(path) != 10 {
+		t.Errorf("path %v has %d steps, want 10", path, len(path))
+	}
+	corner := false
+	for _, waypoint := range path {
+		cell := b.cellOf(waypoint)
+		if cell.Y != 0 && cell.X != 5 {
+			t.Errorf("path %v leaves the corridor at %v", path, waypoint)
+		}
+		corner = corner || (cell.X == 5 && cell.Y == 0)
+	}
+	if !corner {
+		t.Errorf("path %v cuts the corner", path)
+	}
+	if last := b.cellOf(path[len(path)-1]); last != b.cellOf(goal) {
+		t.Errorf("path ends at %v, want %v", last, b.cellOf(goal))
+	}
+	if _, ok := b.findPath(start, Loc{X: tileCentre(b, 2), Y: tileCentre(b, 3)}, false); ok {
+		t.Error("found a path to a tile we know nothing about")
+	}
+}
+
+func BenchmarkFindPath(b *testing.B) {
+	layouts := []struct {
+		name string
+		l    *layout
+	}{
+		{"empty room", emptyRoom(60, 60)},
+		{"maze", maze(61, 61, 1)},
+		{"rooms and corridors", roomsAndCorridors(80, 60, 12, 1)},
+	}
+	for _, tt := range layouts {
+		bot, _ := loadLayout(b, tt.l, nil)
+		start, goal := tt.l.locOf(tt.l.start, bot.tileSize()), tt.l.locOf(tt.l.exit, bot.tileSize())
+		if _, ok := bot.findPath(start, goal, false); !ok {
+			b.Fatalf("%s: no path from the start to the exit", tt.name)
+		}
+		b.Run(tt.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bot.findPath(start, goal, false)
+			}
+		})
+	}
+}
+
+func TestEnemyBlocksPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		h     int // rows of open floor
+		want  string
+		block bool
+	}{
+		// in a one tile corridor there's no way past them, so they have to be dealt with first
+		{"corridor", 1, "enemy", true},
+		// in an open room we can walk round
+		{"room", 5, "exit", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := openFloor(t, 21, tt.h, func(cfg *Config) { cfg.RushExit = true })
+			row := tileCentre(b, tt.h/2)
+			here := Loc{X: tileCentre(b, 0), Y: row}
+			exit := Loc{X: tileCentre(b, 20), Y: row}
+			enemy := Loc{X: tileCentre(b, 10), Y: row}
+			b.handleMessage(fmt.Sprintf("playerupdate:%d,%d,10,10,True", here.X, here.Y))
+			b.handleMessage(fmt.Sprintf("exit:%d,%d", exit.X, exit.Y))
+			b.handleMessage(fmt.Sprintf("nearbyplayer:orc,grunt,%d,%d", enemy.X, enemy.Y))
+			if got := b.enemyBlocksPath(here, exit, enemy); got != tt.block {
+				t.Errorf("enemy blocks the path: %v, want %v", got, tt.block)
+			}
+			if got := b.strategy.Decide(b.Snapshot()).Goal; got != tt.want {
+				t.Errorf("went for %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
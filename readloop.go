@@ -0,0 +1,314 @@
+package gauntletbot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Receive game updates from the sever and update our game state until the context is cancelled.
+// If the connection is lost for good, dials the server again when we're configured to reconnect,
+// and otherwise calls lost and returns
+func (b *Bot) readLoop(ctx context.Context, lost func()) {
+	failures := 0
+	var frames framer
+	for {
+		var msg = make([]byte, readBufferSize)
+		conn := b.transport()
+		n, err := conn.Read(msg)
+		if err != nil {
+			if isPermanent(err) && conn != b.transport() {
+				// the watchdog closed this connection under us, carry on with the new one
+				failures = 0
+				continue
+			}
+			if isPermanent(err) && ctx.Err() != nil {
+				return // we're shutting down and closed it ourselves
+			}
+			if isPermanent(err) && b.cfg.ReconnectTimeout > 0 {
+				Warnf("Connection lost: %v, reconnecting", err)
+				if err := b.redial(); err != nil {
+					Errorf("Reconnect failed: %v", err)
+					failures++
+					time.Sleep(readBackoff(failures))
+				}
+				continue
+			}
+			if isPermanent(err) {
+				Warnf("Connection lost: %v", err)
+				lost()
+				return
+			}
+			Errorf("%v", err)
+			// back off so a persistent fault doesn't spin the CPU
+			failures++
+			time.Sleep(readBackoff(failures))
+		} else {
+			failures = 0
+		}
+		if n > 0 {
+			for _, message := range frames.split(msg[:n], n == len(msg)) {
+				b.handleMessage(message)
+			}
+		}
+	}
+}
+
+// update our game state from one datagram
+func (b *Bot) handleMessage(msg string) {
+	defer b.survive("handling " + strconv.Quote(msg))
+	e, err := b.parseMessage([]byte(msg))
+	msgType, _, _ := strings.Cut(strings.Trim(msg, "\x00 \t\r\n"), ":")
+	if !b.knownMessage(msgType) {
+		msgType = "other" // don't let whatever the server makes up grow the metrics without bound
+	}
+	b.metrics.countMessage(msgType, err == nil)
+	if err != nil {
+		Errorf("%v", err)
+		return
+	}
+	b.applyEvent(e)
+}
+
+// update our game state from something the server told us
+func (b *Bot) applyEvent(e Event) {
+	switch e := e.(type) {
+	case PlayerJoined:
+		// we hear this again every time we rejoin, and perhaps after playerupdates have started,
+		// so only fill in what we don't know yet rather than clobbering what we do
+		b.playerMutex.Lock()
+		p := &b.state.Player
+		if p.Name == "" {
+			p.Name = e.Name
+			p.ID = e.ID
+		} else if e.Name != p.Name {
+			Warnf("Server now calls us %s, still answering to %s", e.Name, p.Name)
+		}
+		if b.state.Updated.IsZero() {
+			if e.Placed {
+				p.Loc = e.Loc
+			}
+			// assume we're fit to get on with it until the first playerupdate says otherwise
+			p.Health = b.cfg.ResumeHealth
+			p.Ammo = b.cfg.ResumeAmmo
+		}
+		b.playerMutex.Unlock()
+		if e.Name != b.cfg.Name {
+			Warnf("Asked to join as %s but the server calls us %s", b.cfg.Name, e.Name)
+		}
+	case PlayerUpdate:
+		b.playerMutex.Lock()
+		prevHealth, hadUpdate := b.state.Player.Health, !b.state.Updated.IsZero()
+		b.state.Updated = b.clock.Now()
+		b.state.Player.Loc = e.Loc
+		b.state.Player.Health = e.Health
+		b.state.Player.Ammo = e.Ammo
+		b.state.Player.HasKey = e.HasKey
+		b.playerMutex.Unlock()
+		b.trackMotion(e.Loc)
+		if hadUpdate && e.Health < prevHealth {
+			b.onDamage(prevHealth, e.Health)
+		}
+	case ExitSeen:
+		b.playerMutex.Lock()
+		if b.state.Player.Exit == nil {
+			exit := e.Loc
+			b.state.Player.Exit = &exit
+		}
+		b.playerMutex.Unlock()
+	case NearbyItem:
+		if !b.noteKey(e) {
+			b.addItem(e.Type, e.Loc.X, e.Loc.Y)
+			b.share(e)
+		}
+	case NearbyPlayer:
+		if b.isMe(e.Name) {
+			// some servers include us in the list of nearby players
+			break
+		}
+		if b.cfg.Teammates[e.Name] {
+			b.setTeammate(e.Name, e.Loc.X, e.Loc.Y)
+			break
+		}
+		b.enemyMutex.Lock()
+		b.state.Enemies[e.Name] = Item{Type: e.Name, Loc: e.Loc, Seen: b.clock.Now(), Facing: e.Facing, Health: e.Health}
+		b.enemyMutex.Unlock()
+		b.recordSighting(e.Name, e.Loc)
+	case NearbyWalls:
+		b.learnTileSize(e.Walls)
+		for _, wall := range e.Walls {
+			b.setWall(wall.X, wall.Y)
+		}
+		b.share(e)
+	case NearbyFloors:
+		b.learnTileSize(e.Floors)
+		for _, floor := range e.Floors {
+			b.setFloor(floor.X, floor.Y)
+		}
+		b.share(e)
+	case RoundEnd:
+		Infof("Round over")
+		b.resetRound(b.cfg.ResetMapOnRound)
+	case RoundStart:
+		Infof("Round starting")
+		b.resetRound(b.cfg.ResetMapOnRound)
+		b.resetObjective()
+		if b.cfg.RejoinOnRound {
+			b.join(b.cfg.Name)
+		}
+	case Unrecognised:
+		debugf("%s", e.Text)
+	}
+}
+
+// if an item is our key, remember where it is, the first time we're told.  Reports whether it was
+func (b *Bot) noteKey(e NearbyItem) bool {
+	if e.Type != b.myKeyName() {
+		return false
+	}
+	b.playerMutex.Lock()
+	defer b.playerMutex.Unlock()
+	if b.state.Player.MyKey == nil {
+		key := e.Loc
+		b.state.Player.MyKey = &key
+	}
+	return true
+}
+
+// framer splits what we read into messages.  A datagram normally holds one message, which may be
+// padded with trailing NULs, but a server could batch several into one separated by newlines, or send one
+// too long for a datagram in several.  NULs inside a message are left for cleanParam to deal with.
+// A datagram that fills our buffer and doesn't end in padding or a newline is taken to be split,
+// and its last message is held back to be completed by the next
+type framer struct {
+	pending string
+}
+
+func (f *framer) split(datagram []byte, full bool) []string {
+	unpadded := strings.TrimRight(string(datagram), "\x00")
+	data := f.pending + unpadded
+	f.pending = ""
+	var messages []string
+	for _, message := range strings.Split(data, "\n") {
+		if message != "" {
+			messages = append(messages, message)
+		}
+	}
+	cut := full && len(unpadded) == len(datagram) && !strings.HasSuffix(data, "\n")
+	if cut && len(messages) > 0 {
+		f.pending = messages[len(messages)-1]
+		messages = messages[:len(messages)-1]
+	}
+	return messages
+}
+
+// how many comma separated parameters each message needs before we can make sense of it
+var minParams = map[string]int{
+	"playerjoined": 1, // name, and usually id,x,y
+	"playerupdate": 5, // x,y,health,ammo,haskey
+	"exit":         2, // x,y
+	"nearbyitem":   3, // type,x,y
+	"nearbyplayer": 4, // name,class,x,y and maybe facing and health
+}
+
+// is this a message type we understand?
+func (b *Bot) knownMessage(msgType string) bool {
+	if _, ok := minParams[msgType]; ok {
+		return true
+	}
+	switch msgType {
+	case "nearbywalls", "nearbyfloors", b.cfg.RoundStartMsg, b.cfg.RoundEndMsg:
+		return true
+	}
+	return false
+}
+
+// errors after which reading the socket again is never going to work
+func isPermanent(err error) bool {
+	return errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF)
+}
+
+// how long to wait after the given number of consecutive read errors: doubling from 10ms, up to a second
+func readBackoff(failures int) time.Duration {
+	if failures > 7 {
+		return time.Second
+	}
+	return 10 * time.Millisecond << (failures - 1)
+}
+
+// the item name our key goes by.  Colours follow the server's name for us, unless
+// it has renamed us to something it doesn't have a colour for, or we've been told our class
+func (b *Bot) myKeyName() string {
+	color, ok := b.cfg.Colors[b.state.Player.Name]
+	if !ok || b.cfg.Class != "" {
+		color, _ = b.cfg.KeyColor()
+	}
+	return color + "key"
+}
+
+// is a player name one of ours?
+func (b *Bot) isMe(name string) bool {
+	return name == b.state.Player.Name || name == b.state.Player.ID
+}
+
+// read the x,y pair starting at params[i], if there is one
+func parseLoc(params []string, i int) (Loc, bool) {
+	if i+1 >= len(params) {
+		return Loc{}, false
+	}
+	x, okX := parseCoord(params[i])
+	y, okY := parseCoord(params[i+1])
+	return Loc{X: x, Y: y}, okX && okY
+}
+
+// read a coordinate the server sends as a float.  Anything that isn't a finite number
+// within maxCoord of the origin would poison every decision we make from it
+func parseCoord(s string) (int, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.Abs(f) > maxCoord {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// tidy up one parameter of a message.  The server pads fields with NULs at times, which the number parsers won't take
+func cleanParam(param string) string {
+	return strings.Trim(param, "\x00 \t\r\n")
+}
+
+// split a message's parameters on commas, cleaning each one
+func splitParams(paramString string) []string {
+	params := strings.Split(paramString, ",")
+	for i, param := range params {
+		params[i] = cleanParam(param)
+	}
+	return params
+}
+
+// split an "x1,y1,x2,y2,..." list into locations.  An empty list is fine; a dangling
+// coordinate or one that isn't a number is logged and skipped
+func coordPairs(msgType string, paramString string) []Loc {
+	if paramString == "" {
+		return nil
+	}
+	params := splitParams(paramString)
+	if len(params)%2 != 0 {
+		Errorf("%s has an odd number of coordinates (%d), ignoring the last", msgType, len(params))
+	}
+	locs := make([]Loc, 0, len(params)/2)
+	for i := 0; i+1 < len(params); i += 2 {
+		x, okX := parseCoord(params[i])
+		y, okY := parseCoord(params[i+1])
+		if !okX || !okY {
+			Errorf("%s has a bad coordinate pair %q,%q", msgType, params[i], params[i+1])
+			continue
+		}
+		locs = append(locs, Loc{X: x, Y: y})
+	}
+	return locs
+}
@@ -0,0 +1,244 @@
+package gauntletbot
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// a Transport whose socket has already been closed under it
+type closedTransport struct{}
+
+func (closedTransport) Read([]byte) (int, error)  { return 0, net.ErrClosed }
+func (closedTransport) Write([]byte) (int, error) { return 0, net.ErrClosed }
+func (closedTransport) Close() error              { return nil }
+
+func TestReadLoopLostWithoutReconnect(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.ReconnectTimeout = 0 })
+	b.conn = closedTransport{}
+	lost := make(chan struct{})
+	go b.readLoop(context.Background(), func() { close(lost) })
+	select {
+	case <-lost:
+	case <-time.After(time.Second):
+		t.Fatal("read loop carried on after the connection closed, with reconnecting off")
+	}
+}
+
+func TestReadLoopReconnects(t *testing.T) {
+	s := newMockServer(t)
+	b, _ := newTestBot(func(cfg *Config) {
+		cfg.Host = "127.0.0.1"
+		cfg.Port = s.port()
+		cfg.Name = "warrior"
+	})
+	b.conn = closedTransport{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.readLoop(ctx, func() { t.Error("gave up on the connection instead of reconnecting") })
+	}()
+	s.expect("requestjoin:", nil)
+	cancel()
+	b.transport().Close()
+	<-done
+}
+
+func TestFramerSplit(t *testing.T) {
+	type read struct {
+		datagram string
+		full     bool
+		want     []string
+	}
+	tests := []struct {
+		name  string
+		reads []read
+	}{
+		{"one message", []read{
+			{"exit:40,60", false, []string{"exit:40,60"}},
+		}},
+		{"padded", []read{
+			{"exit:40,60\x00\x00\x00", true, []string{"exit:40,60"}},
+		}},
+		{"nuls inside a message are kept", []read{
+			{"playerupdate:10\x00,20,5,3,False\x00", false, []string{"playerupdate:10\x00,20,5,3,False"}},
+		}},
+		{"two in one datagram", []read{
+			{"exit:40,60\nnearbyitem:redkey,8,16\n", false, []string{"exit:40,60", "nearbyitem:redkey,8,16"}},
+		}},
+		{"split trailing message", []read{
+			{"exit:40,60\nnearbyitem:red", true, []string{"exit:40,60"}},
+			{"key,8,16", false, []string{"nearbyitem:redkey,8,16"}},
+		}},
+		{"full datagram ending in a newline", []read{
+			{"exit:40,60\n", true, []string{"exit:40,60"}},
+			{"nearbyitem:redkey,8,16", false, []string{"nearbyitem:redkey,8,16"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f framer
+			for i, r := range tt.reads {
+				if got := f.split([]byte(r.datagram), r.full); !reflect.DeepEqual(got, r.want) {
+					t.Errorf("read %d: got %q, want %q", i, got, r.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNULPaddedParams(t *testing.T) {
+	const padded = "playerupdate:10\x00\x00,20\x00,5,3,False\x00\x00"
+	want := Player{Loc: Loc{X: 10, Y: 20}, Health: 5, Ammo: 3}
+	check := func(t *testing.T, p Player) {
+		t.Helper()
+		if p.Loc != want.Loc || p.Health != want.Health || p.Ammo != want.Ammo || p.HasKey {
+			t.Errorf("got %+v, want %+v", p, want)
+		}
+	}
+
+	t.Run("handleMessage", func(t *testing.T) {
+		b, _ := newTestBot(nil)
+		b.handleMessage(padded)
+		check(t, b.snapshotPlayer())
+	})
+
+	t.Run("readLoop", func(t *testing.T) {
+		s := newMockServer(t)
+		b := runTestBot(t, s, nil)
+		s.expect("requestjoin:", nil)
+		s.send(padded)
+		deadline := time.Now().Add(2 * time.Second)
+		for b.snapshotPlayer().Loc != want.Loc && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		check(t, b.snapshotPlayer())
+	})
+}
+
+func TestMessageMetricsLabels(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.RoundEndMsg = "gameover" })
+	for _, msg := range []string{
+		"playerupdate:10,20,5,3,False",
+		"playerupdate:ten,20,5,3,False",
+		"nearbywalls:0,0",
+		"gameover",
+		"scores:warrior,10",
+		"chat:hello",
+		"\x00\x00",
+		"roundend", // not the end of round message we were given
+	} {
+		b.handleMessage(msg)
+	}
+	b.metrics.mu.Lock()
+	defer b.metrics.mu.Unlock()
+	want := map[string]int64{"playerupdate": 2, "nearbywalls": 1, "gameover": 1, "other": 4}
+	if !reflect.DeepEqual(b.metrics.messages, want) {
+		t.Errorf("counted messages %v, want %v", b.metrics.messages, want)
+	}
+	if b.metrics.parseErrors != 1 {
+		t.Errorf("counted %d parse errors, want 1", b.metrics.parseErrors)
+	}
+}
+
+func TestNearbyPlayerIsMe(t *testing.T) {
+	tests := []struct {
+		player string
+		enemy  bool
+	}{
+		{"nearbyplayer:warrior,warrior,100,100", false},
+		{"nearbyplayer:7,warrior,100,100", false}, // by the id the server gave us
+		{"nearbyplayer:orc,grunt,140,100", true},
+		{"nearbyplayer:warrior2,warrior,140,100", true},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBot(func(cfg *Config) { cfg.Name = "warrior" })
+		b.handleMessage("playerjoined:warrior,7,100,100")
+		b.handleMessage(tt.player)
+		b.enemyMutex.Lock()
+		enemies := len(b.state.Enemies)
+		b.enemyMutex.Unlock()
+		if (enemies == 1) != tt.enemy {
+			t.Errorf("%q: %d enemies known, want an enemy %v", tt.player, enemies, tt.enemy)
+		}
+	}
+}
+
+func TestJoinThenUpdate(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) {
+		cfg.Name = "warrior"
+		cfg.ResumeHealth = 6
+		cfg.ResumeAmmo = 4
+	})
+	// the server had another warrior, so renamed us
+	b.handleMessage("playerjoined:warrior_2,7,40,60")
+	p := b.snapshotPlayer()
+	want := Player{Name: "warrior_2", ID: "7", Loc: Loc{X: 40, Y: 60}, Health: 6, Ammo: 4}
+	if p.Name != want.Name || p.ID != want.ID || p.Loc != want.Loc || p.Health != want.Health || p.Ammo != want.Ammo {
+		t.Fatalf("after joining know %+v, want %+v", p, want)
+	}
+	if key := b.myKeyName(); key != "redkey" {
+		t.Errorf("our key is %s under a name with no colour, want the redkey we asked for", key)
+	}
+
+	b.handleMessage("playerupdate:50,60,3,2,False")
+	// joining again mustn't undo what the playerupdate told us
+	b.handleMessage("playerjoined:warrior_3,8,0,0")
+	p = b.snapshotPlayer()
+	want = Player{Name: "warrior_2", ID: "7", Loc: Loc{X: 50, Y: 60}, Health: 3, Ammo: 2}
+	if p.Name != want.Name || p.ID != want.ID || p.Loc != want.Loc || p.Health != want.Health || p.Ammo != want.Ammo {
+		t.Errorf("after rejoining know %+v, want %+v", p, want)
+	}
+}
+
+func TestParseCoord(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+		ok   bool
+	}{
+		{"40", 40, true},
+		{"40.9", 40, true},
+		{"-40.9", -40, true},
+		{"1e6", maxCoord, true},
+		{"-1000000", -maxCoord, true},
+		{"1000000.5", 0, false},
+		{"1e308", 0, false},
+		{"-1e400", 0, false},
+		{"NaN", 0, false},
+		{"Inf", 0, false},
+		{"-Infinity", 0, false},
+		{"", 0, false},
+		{"forty", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := parseCoord(tt.s); got != tt.want || ok != tt.ok {
+			t.Errorf("parseCoord(%q) = %d, %v, want %d, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBadPositionKeepsLast(t *testing.T) {
+	b, _ := newTestBot(nil)
+	b.handleMessage("playerupdate:10,20,5,3,False")
+	b.handleMessage("nearbyplayer:orc,grunt,40,20")
+	for _, msg := range []string{
+		"playerupdate:NaN,20,4,3,False",
+		"playerupdate:10,Inf,4,3,False",
+		"playerupdate:1e300,-1e300,4,3,False",
+		"playerupdate:99999999999999999999,20,4,3,False",
+		"nearbyplayer:orc,grunt,NaN,20",
+		"nearbyplayer:orc,grunt,40,1e12",
+	} {
+		b.handleMessage(msg)
+	}
+	if p := b.snapshotPlayer(); p.Loc != (Loc{X: 10, Y: 20}) || p.Health != 5 {
+		t.Errorf("after nonsense positions we're at %v with health %d, want (10,20) and 5 as before", p.Loc, p.Health)
+	}
+	if e := b.snapshotEnemy(); e.Loc == nil || *e.Loc != (Loc{X: 40, Y: 20}) {
+		t.Errorf("after nonsense positions the enemy is at %v, want (40,20) as before", e.Loc)
+	}
+}
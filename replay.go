@@ -0,0 +1,116 @@
+package gauntletbot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// a datagram we received, and when, as read back from a -record or -capture file
+type recordedDatagram struct {
+	At      time.Time
+	Payload string
+}
+
+// read the datagrams the server sent us from a file in the capture format, skipping anything we sent
+func readRecording(r io.Reader) ([]recordedDatagram, error) {
+	var recording []recordedDatagram
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		fields := strings.SplitN(text, " ", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("line %d: expected a time, direction, length and payload: %s", line, text)
+		}
+		if fields[1] != "in" {
+			continue
+		}
+		at, err := time.Parse(captureTimeFormat, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		payload, err := strconv.Unquote(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad payload %s", line, fields[3])
+		}
+		recording = append(recording, recordedDatagram{At: at, Payload: payload})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return recording, nil
+}
+
+// replay is a Transport that hands the read loop a recording at the pace it was made,
+// then reports the end of the file as a lost connection.  What we write is dropped
+type replay struct {
+	clock     Clock
+	recording []recordedDatagram
+	next      int
+	started   time.Time
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newReplay(recording []recordedDatagram, clock Clock) *replay {
+	return &replay{clock: clock, recording: recording, closed: make(chan struct{})}
+}
+
+func (r *replay) Read(p []byte) (int, error) {
+	if r.next >= len(r.recording) {
+		select {
+		case <-r.closed:
+			return 0, net.ErrClosed
+		default:
+			return 0, io.EOF
+		}
+	}
+	d := r.recording[r.next]
+	if r.next == 0 {
+		r.started = r.clock.Now()
+	}
+	// keep the original gaps between datagrams, on the bot's clock
+	wait := d.At.Sub(r.recording[0].At) - r.clock.Now().Sub(r.started)
+	select {
+	case <-r.closed:
+		return 0, net.ErrClosed
+	case <-r.clock.After(wait):
+	}
+	r.next++
+	return copy(p, d.Payload), nil
+}
+
+func (r *replay) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (r *replay) Close() error {
+	r.closeOnce.Do(func() { close(r.closed) })
+	return nil
+}
+
+// Replay has Run play back a recording of a game in place of connecting to a server
+func (b *Bot) Replay(r io.Reader) error {
+	recording, err := readRecording(r)
+	if err != nil {
+		return err
+	}
+	if len(recording) == 0 {
+		return fmt.Errorf("nothing received in the recording to replay")
+	}
+	b.connMutex.Lock()
+	defer b.connMutex.Unlock()
+	b.conn = newReplay(recording, b.clock)
+	b.connected = b.clock.Now()
+	Infof("Replaying %d datagrams received over %s", len(recording), recording[len(recording)-1].At.Sub(recording[0].At))
+	return nil
+}
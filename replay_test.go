@@ -0,0 +1,121 @@
+package gauntletbot
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// a Transport that reads back the datagrams it was given, then reports the socket closed
+type scriptedTransport struct {
+	datagrams []string
+}
+
+func (s *scriptedTransport) Read(p []byte) (int, error) {
+	if len(s.datagrams) == 0 {
+		return 0, net.ErrClosed
+	}
+	n := copy(p, s.datagrams[0])
+	s.datagrams = s.datagrams[1:]
+	return n, nil
+}
+
+func (s *scriptedTransport) Write(p []byte) (int, error) { return len(p), nil }
+func (s *scriptedTransport) Close() error                { return nil }
+
+// run the read loop until the connection runs dry
+func readUntilLost(t *testing.T, b *Bot) {
+	t.Helper()
+	lost := make(chan struct{})
+	go b.readLoop(context.Background(), func() { close(lost) })
+	select {
+	case <-lost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("read loop still running after the last datagram")
+	}
+}
+
+// what a game has taught the bot, leaving out when the snapshot was taken
+func learned(b *Bot) Snapshot {
+	s := b.Snapshot()
+	s.Taken = time.Time{}
+	return s
+}
+
+func TestRecordReplayRoundTrip(t *testing.T) {
+	configure := func(cfg *Config) {
+		cfg.Name = "warrior"
+		cfg.ReconnectTimeout = 0
+	}
+	b, _ := newTestBot(configure)
+	datagrams := []string{
+		"playerupdate:10,20,5,3,False",
+		"nearbywalls:0,0,8,0,16,0",
+		"nearbyfloors:8,8,16,8",
+		"exit:40,60",
+		"nearbyitem:" + b.myKeyName() + ",24,32",
+		"nearbyitem:ammo,8,16",
+		"nearbyplayer:orc,grunt,48,16",
+		"playerupdate:12,20,4,2,False",
+	}
+
+	var recording bytes.Buffer
+	b.conn = newRecorder(&scriptedTransport{datagrams: append([]string(nil), datagrams...)}, &recording, b.clock)
+	readUntilLost(t, b)
+
+	replayed, _ := newTestBot(configure)
+	if err := replayed.Replay(&recording); err != nil {
+		t.Fatal(err)
+	}
+	readUntilLost(t, replayed)
+
+	want, got := learned(b), learned(replayed)
+	if want.Player.Exit == nil || want.Player.MyKey == nil || want.Walls != 3 || want.Items["ammo"] != 1 {
+		t.Fatalf("the recorded bot didn't learn the game it was sent: %+v", want)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replay learned %+v, want %+v as when it was recorded", got, want)
+	}
+}
+
+func TestReplayKeepsTime(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	r := newReplay([]recordedDatagram{
+		{At: start, Payload: "playerupdate:10,20,5,3,False"},
+		{At: start.Add(10 * time.Second), Payload: "exit:40,60"},
+	}, clock)
+	buf := make([]byte, 64)
+	if n, err := r.Read(buf); err != nil || string(buf[:n]) != "playerupdate:10,20,5,3,False" {
+		t.Fatalf("first Read() = %q, %v", buf[:n], err)
+	}
+
+	read := make(chan string)
+	go func() {
+		n, _ := r.Read(buf)
+		read <- string(buf[:n])
+	}()
+	for give := time.Now().Add(5 * time.Second); clock.waiting() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(give) {
+			t.Fatal("the replay isn't waiting on its clock for the next datagram")
+		}
+	}
+	clock.Advance(9 * time.Second)
+	select {
+	case got := <-read:
+		t.Fatalf("read %q a second early", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	select {
+	case got := <-read:
+		if got != "exit:40,60" {
+			t.Errorf("second Read() = %q, want the exit", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("still waiting after the clock reached the next datagram")
+	}
+}
@@ -0,0 +1,46 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// deferred around a unit of work: in -resilient mode, logs a panic along with
+// what we were doing and lets the loop carry on.  Otherwise the panic goes on up
+func (b *Bot) survive(what string) {
+	if !b.cfg.Resilient {
+		return
+	}
+	if r := recover(); r != nil {
+		b.logPanic(what, r)
+	}
+}
+
+// run one tick of the write loop.  If it panics in -resilient mode, pause for a tick
+// so a fault that recurs every time can't spin the CPU or flood the server
+func (b *Bot) think(tick func()) {
+	defer func() {
+		if !b.cfg.Resilient {
+			return
+		}
+		if r := recover(); r != nil {
+			b.logPanic("deciding what to do", r)
+			time.Sleep(b.tickDuration())
+		}
+	}()
+	tick()
+}
+
+func (b *Bot) logPanic(what string, r interface{}) {
+	p := b.snapshotPlayer()
+	Errorf("PANIC while %s: %v\nplayer %+v, enemy %s, exit %s, key %s\n%s",
+		what, r, p, describeLoc(b.snapshotEnemy().Loc), describeLoc(p.Exit), describeLoc(p.MyKey), debug.Stack())
+}
+
+func describeLoc(loc *Loc) string {
+	if loc == nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("(%d,%d)", loc.X, loc.Y)
+}
@@ -0,0 +1,48 @@
+package gauntletbot
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestSurvivePanics(t *testing.T) {
+	var logged bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&logged)
+	defer log.SetOutput(prev)
+
+	b, _ := newTestBot(func(cfg *Config) { cfg.Resilient = true })
+	b.OnDamage(func(int, int) { panic("hook blew up") })
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	b.handleMessage("playerupdate:100,100,8,10,False")
+	// and the read loop carries on with the next message
+	b.handleMessage("exit:40,60")
+	if p := b.snapshotPlayer(); p.Exit == nil || p.Health != 8 {
+		t.Errorf("after the panic know %+v, want the health it came with and the exit after", p)
+	}
+	b.think(func() { panic("tick blew up") })
+
+	for _, want := range []string{
+		`PANIC while handling "playerupdate:100,100,8,10,False": hook blew up`,
+		"PANIC while deciding what to do: tick blew up",
+	} {
+		if !strings.Contains(logged.String(), want) {
+			t.Errorf("log doesn't mention %q:\n%s", want, logged.String())
+		}
+	}
+}
+
+func TestPanicsWithoutResilient(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.Resilient = false })
+	b.OnDamage(func(int, int) { panic("hook blew up") })
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	defer func() {
+		if r := recover(); r != "hook blew up" {
+			t.Errorf("recovered %v, want the hook's panic to reach us", r)
+		}
+	}()
+	b.handleMessage("playerupdate:100,100,8,10,False")
+	t.Error("survived a panic without -resilient")
+}
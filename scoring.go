@@ -0,0 +1,207 @@
+package gauntletbot
+
+import (
+	"math"
+)
+
+// Candidate is something we could head for, as offered to a Scorer
+type Candidate struct {
+	Type     string // key, exit, enemy, or ammo, food or any other item type the server reports
+	Loc      Loc
+	Distance float64 // distance from where we are: straight line, or for items walking distance with PathDistance
+	Count    int     // how many items are clustered here, 1 for anything else
+}
+
+// Scorer rates a candidate target; the highest score wins, and nothing scoring 0 or less is ever chosen.
+// It's asked to choose between clusters of items of one type, between the objective and a fight at the
+// bottom of DefaultStrategy's ladder, and with Weighted between everything we could go after.  It's called
+// from the write loop with a snapshot of the game.  Swap in your own through Config.Scorer
+type Scorer func(c Candidate, s Snapshot) float64
+
+// defaultScore is the Scorer we use unless Config.Scorer replaces it.  It prefers whatever item we need
+// most, then whichever cluster has the most in it, then whatever is closest, and always puts the objective
+// before a fight.  With Weighted, everything scores its weight from Config.Weights times how urgently we
+// need it, divided by 1 + its distance over DistanceScale
+func (b *Bot) defaultScore(c Candidate, s Snapshot) float64 {
+	if !b.cfg.Weighted {
+		switch c.Type {
+		case "key", "exit":
+			return 2
+		case "enemy":
+			return 1
+		}
+		return need(c.Type, s.Player) * float64(max(c.Count, 1)) / (1 + c.Distance/100)
+	}
+	w := b.cfg.Weights
+	weight, urgency := 1.0, need(c.Type, s.Player)
+	switch c.Type {
+	case "key":
+		weight = w.Key
+	case "exit":
+		weight = w.Exit
+	case "enemy":
+		weight, urgency = w.Enemy, b.aggressionFactor(s.Player.Health)
+	case "ammo":
+		weight = w.Ammo
+	case "food":
+		weight = w.Food
+	}
+	return weight * urgency * float64(max(c.Count, 1)) / (1 + c.Distance/w.DistanceScale)
+}
+
+// how badly do we want an item of this type right now?
+func need(itemType string, p Player) float64 {
+	switch itemType {
+	case "ammo":
+		if p.Ammo == 0 {
+			return 5
+		}
+		return 1
+	case "food":
+		return 1 + math.Max(0, float64(5-p.Health))
+	}
+	return 1
+}
+
+// of the items we can see, which does the scorer like best?  Items within ClusterRadius of each other are
+// scored together and we head for the middle of the group, then for the nearest member once we're among them
+func (b *Bot) bestVisibleItem(from Loc, items []Item) (*Item, bool) {
+	var visible []Item
+	for _, item := range items {
+		if b.canSeeItem(from, item.Loc) {
+			visible = append(visible, item)
+		}
+	}
+	radius := float64(b.cfg.ClusterRadius)
+	var best *Item
+	bestScore := math.Inf(-1)
+	for _, group := range clusterItems(visible, radius) {
+		straight := b.distance(from, group.centre)
+		c := Candidate{Type: group.members[0].Type, Loc: group.centre, Distance: straight, Count: len(group.members)}
+		if b.cfg.PathDistance {
+			c.Distance = b.walkingDistance(from, group.centre)
+		}
+		score := b.scorer(c, b.tickSnapshot())
+		b.tracef("scored %d %s at (%d,%d): %.3f", c.Count, c.Type, c.Loc.X, c.Loc.Y, score)
+		if score > bestScore {
+			target := group.members[0]
+			if len(group.members) > 1 {
+				target.Loc = group.centre
+				if straight <= radius {
+					if nearest, ok := b.nearestVisibleItem(from, group.members); ok {
+						target = *nearest
+					}
+				}
+			}
+			best = &target
+			bestScore = score
+		}
+	}
+	return best, best != nil
+}
+
+// a group of items close enough together to pick up in one trip
+type cluster struct {
+	members []Item
+	centre  Loc
+}
+
+// group items greedily: each item not yet in a cluster starts one and takes every other
+// unclaimed item within radius of it.  With a radius of 0 every item is on its own
+func clusterItems(items []Item, radius float64) []cluster {
+	claimed := make([]bool, len(items))
+	var clusters []cluster
+	for i := range items {
+		if claimed[i] {
+			continue
+		}
+		claimed[i] = true
+		group := cluster{members: []Item{items[i]}}
+		for j := i + 1; j < len(items); j++ {
+			if radius > 0 && !claimed[j] && distanceBetween(items[i].Loc, items[j].Loc) <= radius {
+				claimed[j] = true
+				group.members = append(group.members, items[j])
+			}
+		}
+		group.centre = centroid(group.members)
+		clusters = append(clusters, group)
+	}
+	return clusters
+}
+
+// the average position of some items
+func centroid(items []Item) Loc {
+	var x, y int
+	for _, item := range items {
+		x += item.Loc.X
+		y += item.Loc.Y
+	}
+	return Loc{X: x / len(items), Y: y / len(items)}
+}
+
+// the item we can see that's the shortest straight line away, or false if we can't see any of them
+func (b *Bot) nearestVisibleItem(from Loc, items []Item) (*Item, bool) {
+	var nearest *Item
+	for i, item := range items {
+		if b.canSeeItem(from, item.Loc) && (nearest == nil || distanceBetween(from, item.Loc) < distanceBetween(from, nearest.Loc)) {
+			nearest = &items[i]
+		}
+	}
+	return nearest, nearest != nil
+}
+
+// TargetWeights tunes weighted target selection.  With the default Scorer each target we could go after scores
+// its weight, times how urgently we need it, divided by 1 + its distance over DistanceScale
+type TargetWeights struct {
+	Key           float64
+	Exit          float64
+	Ammo          float64
+	Food          float64
+	Enemy         float64
+	DistanceScale float64 // at this distance a target scores half what it would on top of us
+}
+
+// DefaultWeights put the objectives first, while leaving a pickup right next to us worth the detour
+func DefaultWeights() TargetWeights {
+	return TargetWeights{Key: 3, Exit: 4, Ammo: 1, Food: 1, Enemy: 1.5, DistanceScale: 100}
+}
+
+// what the scorer makes of going after a target from here.  Items are scored a cluster at a time by bestVisibleItem
+func (b *Bot) scoreTarget(target string, here Loc, loc Loc) float64 {
+	c := Candidate{Type: target, Loc: loc, Distance: b.distance(here, loc), Count: 1}
+	return b.scorer(c, b.tickSnapshot())
+}
+
+// in place of the bottom of the priority ladder, score everything we could go after against each other
+// and go after the best.  Anything we can't act on (an item we can't see, an exit that won't let us out)
+// doesn't get a score.  With nothing to score, it's the enemy, as at the bottom of the ladder
+func (b *Bot) weightedTarget(p Player, fallback string) string {
+	here := b.selfLoc()
+	bestTarget := "enemy"
+	bestScore := 0.0
+	consider := func(target string, loc Loc) {
+		score := b.scoreTarget(target, here, loc)
+		b.tracef("weighed %s at (%d,%d): %.3f", target, loc.X, loc.Y, score)
+		if score > bestScore {
+			bestTarget = target
+			bestScore = score
+		}
+	}
+	if !p.HasKey && p.MyKey != nil && fallback == "" {
+		consider("key", *p.MyKey)
+	}
+	if p.Exit != nil && (p.HasKey || (fallback == "exit" && !b.exitLocked)) {
+		consider("exit", *p.Exit)
+	}
+	for _, itemType := range []string{"ammo", "food"} {
+		if item, ok := b.bestVisibleItem(here, b.itemsOf(itemType)); ok {
+			consider(itemType, item.Loc)
+		}
+	}
+	// engagement range is straight line whatever the metric, as at the bottom of the ladder
+	if enemy, ok := b.enemyInSight(here); ok && distanceBetween(here, enemy) <= float64(b.engageDistance(p.Health)) {
+		consider("enemy", enemy)
+	}
+	b.tracef("chose %s: it weighed the most, at %.3f", bestTarget, bestScore)
+	return bestTarget
+}
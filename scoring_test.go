@@ -0,0 +1,212 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// a scorer that wants the opposite of the default: fights over objectives, and far items over near ones
+func invertedScorer(c Candidate, s Snapshot) float64 {
+	switch c.Type {
+	case "key", "exit":
+		return 1
+	case "enemy":
+		return 2
+	}
+	return c.Distance
+}
+
+func TestScorerChoosesTarget(t *testing.T) {
+	for _, weighted := range []bool{false, true} {
+		for _, tt := range []struct {
+			scorer Scorer
+			want   string
+		}{
+			{nil, "key"},
+			{invertedScorer, "enemy"},
+		} {
+			b, _ := newTestBot(func(cfg *Config) {
+				cfg.Weighted = weighted
+				cfg.Scorer = tt.scorer
+			})
+			b.handleMessage("playerupdate:100,100,10,10,False")
+			b.handleMessage("nearbyitem:" + b.myKeyName() + ",120,100")
+			b.handleMessage("nearbyplayer:orc,grunt,300,100")
+			if got := b.strategy.Decide(b.Snapshot()).Goal; got != tt.want {
+				t.Errorf("weighted %v, inverted %v: chose %s, want %s", weighted, tt.scorer != nil, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestScorerChoosesItem(t *testing.T) {
+	near, far := Loc{X: 120, Y: 100}, Loc{X: 300, Y: 100}
+	for _, tt := range []struct {
+		scorer Scorer
+		want   Loc
+	}{
+		{nil, near},
+		{invertedScorer, far},
+	} {
+		b, _ := newTestBot(func(cfg *Config) { cfg.Scorer = tt.scorer })
+		b.handleMessage("playerupdate:100,100,10,10,False")
+		b.addItem("ammo", far.X, far.Y)
+		b.addItem("ammo", near.X, near.Y)
+		item, ok := b.bestVisibleItem(b.selfLoc(), b.itemsOf("ammo"))
+		if !ok || item.Loc != tt.want {
+			t.Errorf("inverted %v: chose %v, want %v", tt.scorer != nil, item, tt.want)
+		}
+	}
+}
+
+func TestWeightedTargetMetric(t *testing.T) {
+	// ammo straight along from us and food off on the diagonal, a shade further as the crow flies
+	for metric, want := range map[string]string{"euclidean": "ammo", "manhattan": "ammo", "chebyshev": "food"} {
+		b, _ := newTestBot(func(cfg *Config) {
+			cfg.Weighted = true
+			cfg.ClusterRadius = 0
+			cfg.DistanceMetric = metric
+		})
+		b.handleMessage("playerupdate:100,100,10,10,False")
+		b.addItem("ammo", 200, 100)
+		b.addItem("food", 175, 175)
+		if got := b.weightedTarget(b.snapshotPlayer(), ""); got != want {
+			t.Errorf("%s: chose %s, want %s", metric, got, want)
+		}
+	}
+}
+
+func TestClusterItems(t *testing.T) {
+	items := []Item{
+		{Type: "ammo", Loc: Loc{X: 0, Y: 0}},
+		{Type: "ammo", Loc: Loc{X: 10, Y: 0}},
+		{Type: "ammo", Loc: Loc{X: 100, Y: 100}},
+		{Type: "ammo", Loc: Loc{X: 30, Y: 0}},
+	}
+	// each cluster as indexes into items, and its centre
+	type want struct {
+		members []int
+		centre  Loc
+	}
+	tests := []struct {
+		radius float64
+		want   []want
+	}{
+		{0, []want{{[]int{0}, Loc{X: 0, Y: 0}}, {[]int{1}, Loc{X: 10, Y: 0}}, {[]int{2}, Loc{X: 100, Y: 100}}, {[]int{3}, Loc{X: 30, Y: 0}}}},
+		// greedy from the first item: 30,0 is within reach of 10,0 but not of 0,0 which started the cluster
+		{20, []want{{[]int{0, 1}, Loc{X: 5, Y: 0}}, {[]int{2}, Loc{X: 100, Y: 100}}, {[]int{3}, Loc{X: 30, Y: 0}}}},
+		{30, []want{{[]int{0, 1, 3}, Loc{X: 13, Y: 0}}, {[]int{2}, Loc{X: 100, Y: 100}}}},
+		{200, []want{{[]int{0, 1, 2, 3}, Loc{X: 35, Y: 25}}}},
+	}
+	for _, tt := range tests {
+		got := clusterItems(items, tt.radius)
+		var gotWant []want
+		for _, c := range got {
+			w := want{centre: c.centre}
+			for _, m := range c.members {
+				for i, item := range items {
+					if item == m {
+						w.members = append(w.members, i)
+					}
+				}
+			}
+			gotWant = append(gotWant, w)
+		}
+		if !reflect.DeepEqual(gotWant, tt.want) {
+			t.Errorf("radius %g: clustered %v, want %v", tt.radius, gotWant, tt.want)
+		}
+	}
+	if got := clusterItems(nil, 20); len(got) != 0 {
+		t.Errorf("clustered nothing into %v", got)
+	}
+}
+
+func TestNearestVisibleItem(t *testing.T) {
+	b, _ := newTestBot(nil)
+	here := Loc{X: 100, Y: 100}
+	if item, ok := b.nearestVisibleItem(here, nil); ok {
+		t.Errorf("found %v among no items", item)
+	}
+	// a wall between us and the nearest
+	b.setWall(130, 100)
+	items := []Item{
+		{Type: "ammo", Loc: Loc{X: 300, Y: 100}},
+		{Type: "ammo", Loc: Loc{X: 160, Y: 100}},
+		{Type: "ammo", Loc: Loc{X: 100, Y: 250}},
+	}
+	if item, ok := b.nearestVisibleItem(here, items); !ok || item.Loc != (Loc{X: 100, Y: 250}) {
+		t.Errorf("chose %v, want the nearest we can see at (100,250)", item)
+	}
+	if item, ok := b.nearestVisibleItem(here, items[:2]); ok {
+		t.Errorf("chose %v, which is behind the wall or further along it", item)
+	}
+}
+
+func TestClusterTarget(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.ClusterRadius = 40 })
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	b.addItem("ammo", 160, 100) // nearer, but on its own
+	for _, loc := range []Loc{{X: 100, Y: 200}, {X: 120, Y: 200}, {X: 110, Y: 220}} {
+		b.addItem("ammo", loc.X, loc.Y)
+	}
+	item, ok := b.bestVisibleItem(b.selfLoc(), b.itemsOf("ammo"))
+	if want := (Loc{X: 110, Y: 206}); !ok || item.Loc != want {
+		t.Fatalf("chose %v, want the middle of the group of three at %v", item, want)
+	}
+
+	// among them, pick them up one at a time
+	b.handleMessage("playerupdate:115,185,10,10,False")
+	item, ok = b.bestVisibleItem(b.selfLoc(), b.itemsOf("ammo"))
+	if want := (Loc{X: 120, Y: 200}); !ok || item.Loc != want {
+		t.Errorf("chose %v from inside the group, want the nearest of it at %v", item, want)
+	}
+
+	// with no clustering, the nearest single item wins
+	b.cfg.ClusterRadius = 0
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	item, ok = b.bestVisibleItem(b.selfLoc(), b.itemsOf("ammo"))
+	if want := (Loc{X: 160, Y: 100}); !ok || item.Loc != want {
+		t.Errorf("chose %v without clustering, want the nearest at %v", item, want)
+	}
+}
+
+func TestWeightedTargetEdges(t *testing.T) {
+	tests := []struct {
+		name     string
+		weights  string
+		health   int
+		key      bool // can we see our key, just along from us
+		fallback string
+		want     string
+	}{
+		// the case the ladder got wrong: health down to 1, food far off, and ammo right beside us
+		{"adjacent ammo beats distant food", "", 1, false, "", "ammo"},
+		{"unless food matters that much more", "food:5", 1, false, "", "food"},
+		{"the key outweighs both", "", 1, true, "", "key"},
+		{"a key weighted to nothing is never chosen", "key:0", 10, true, "", "ammo"},
+		{"nor one we've fallen back from", "", 10, true, "exit", "exit"},
+		{"nothing worth anything leaves us exploring", "key:0,exit:0,ammo:0,food:0", 10, true, "", "enemy"},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBot(func(cfg *Config) {
+			cfg.Weighted = true
+			cfg.ClusterRadius = 0
+			cfg.ExitNeedsKey = false
+			if err := cfg.ParseWeights(tt.weights); err != nil {
+				t.Fatal(err)
+			}
+		})
+		b.handleMessage(fmt.Sprintf("playerupdate:100,100,%d,10,False", tt.health))
+		if tt.key {
+			b.handleMessage("nearbyitem:" + b.myKeyName() + ",150,100")
+		}
+		// without the key, an exit that might let us out only counts when we've fallen back to it
+		b.handleMessage("exit:100,300")
+		b.addItem("ammo", 105, 100)
+		b.addItem("food", 100, 1100)
+		if got := b.weightedTarget(b.snapshotPlayer(), tt.fallback); got != tt.want {
+			t.Errorf("%s: chose %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
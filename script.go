@@ -0,0 +1,95 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScriptStep is one objective in a scripted run
+type ScriptStep struct {
+	Goal string // goto, ammo, food, key or exit
+	Loc  Loc    // where to go, for goto
+}
+
+// scriptRun works through a fixed list of objectives in order, in place of DefaultStrategy's own
+// judgement, for reproducible runs.  Each step ends once it's done or after Timeout, whichever is first.
+// Only the write loop uses it
+type scriptRun struct {
+	Steps   []ScriptStep
+	Timeout time.Duration // 0 waits for each step however long it takes
+
+	current int
+	started time.Time
+	before  Player // how we were when the step started, to tell when we've picked something up
+}
+
+// ParseScript reads a list of steps like "goto:100,200;ammo;exit"
+func ParseScript(script string) ([]ScriptStep, error) {
+	var steps []ScriptStep
+	for _, part := range strings.Split(script, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		goal, coords, _ := strings.Cut(part, ":")
+		step := ScriptStep{Goal: goal}
+		switch goal {
+		case "goto":
+			xs, ys, ok := strings.Cut(coords, ",")
+			x, errX := strconv.Atoi(strings.TrimSpace(xs))
+			y, errY := strconv.Atoi(strings.TrimSpace(ys))
+			if !ok || errX != nil || errY != nil {
+				return nil, fmt.Errorf("bad script step %q, want goto:x,y", part)
+			}
+			step.Loc = Loc{X: x, Y: y}
+		case "ammo", "food", "key", "exit":
+		default:
+			return nil, fmt.Errorf("unknown script step %q", part)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// the step we should be working on, moving on past any that are done or have run out of time.
+// false once the script is finished
+func (s *scriptRun) step(b *Bot, p Player) (ScriptStep, bool) {
+	for s.current < len(s.Steps) {
+		step := s.Steps[s.current]
+		if s.started.IsZero() {
+			s.started = b.clock.Now()
+			s.before = p
+			Infof("Script step %d: %s", s.current+1, step.Goal)
+			return step, true
+		}
+		if s.Timeout > 0 && b.clock.Now().Sub(s.started) > s.Timeout {
+			Infof("Script step %d (%s) timed out", s.current+1, step.Goal)
+		} else if !s.done(b, step, p) {
+			return step, true
+		}
+		s.current++
+		s.started = time.Time{}
+	}
+	return ScriptStep{}, false
+}
+
+func (s *scriptRun) done(b *Bot, step ScriptStep, p Player) bool {
+	arrived := func(loc *Loc) bool {
+		return loc != nil && distanceBetween(p.Loc, *loc) <= float64(b.tileSize())
+	}
+	switch step.Goal {
+	case "goto":
+		return arrived(&step.Loc)
+	case "ammo":
+		return p.Ammo > s.before.Ammo
+	case "food":
+		return p.Health > s.before.Health
+	case "key":
+		return p.HasKey
+	case "exit":
+		return arrived(p.Exit)
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package gauntletbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SelfTest checks the server lets us join, and that it moves us when asked, connecting first if needed.
+// The connection is closed on return.  It can't be run with DryRun, when nothing we send reaches the server
+func (b *Bot) SelfTest() error {
+	if b.cfg.DryRun {
+		return errors.New("can't self test with -dryrun, our join and moveto would never reach the server")
+	}
+	if b.transport() == nil {
+		if err := b.Connect(); err != nil {
+			return err
+		}
+	}
+	defer func() { b.transport().Close() }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.readLoop(ctx, func() {})
+
+	timeout := 5 * time.Second
+	if !waitFor(timeout, func() bool { return b.snapshotPlayer().Name != "" }) {
+		return fmt.Errorf("no playerjoined within %s", timeout)
+	}
+	Infof("Joined as %s", b.snapshotPlayer().Name)
+	if !waitFor(timeout, func() bool { return !b.lastUpdate().IsZero() }) {
+		return fmt.Errorf("no playerupdate within %s", timeout)
+	}
+	start := b.snapshotPlayer().Loc
+	b.moveTo(Loc{X: start.X + 20, Y: start.Y + 20})
+	if !waitFor(timeout, func() bool { return b.snapshotPlayer().Loc != start }) {
+		return fmt.Errorf("still at (%d,%d) %s after moveto", start.X, start.Y, timeout)
+	}
+	Infof("Moved from (%d,%d) to (%d,%d)", start.X, start.Y, b.snapshotPlayer().Loc.X, b.snapshotPlayer().Loc.Y)
+	return nil
+}
+
+// poll until the condition holds, giving up after the timeout
+func waitFor(timeout time.Duration, condition func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return true
+}
@@ -0,0 +1,15 @@
+package gauntletbot
+
+import "testing"
+
+func TestSelfTestDryRun(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.DryRun = true })
+	conn := &recordingTransport{}
+	b.conn = conn
+	if err := b.SelfTest(); err == nil {
+		t.Error("self test passed with nothing sent to the server")
+	}
+	if sent := conn.sent(); len(sent) != 0 {
+		t.Errorf("sent %q on a dry run", sent)
+	}
+}
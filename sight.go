@@ -0,0 +1,198 @@
+package gauntletbot
+
+import (
+	"math"
+	"time"
+)
+
+// a cached line of sight check between two tiles
+type sightLine struct {
+	From Loc
+	To   Loc
+}
+
+type sighting struct {
+	Visible bool
+	Checked time.Time
+}
+
+// check whether we have line of sight to an item (i.e. a wall is not in the way)
+// only the walls in the index buckets along the line are checked.
+// results are cached per pair of tiles for a short while, as we ask the same questions tick after tick
+func (b *Bot) canSeeItem(playerLoc Loc, itemLoc Loc) bool {
+	key := sightLine{From: b.cellOf(playerLoc), To: b.cellOf(itemLoc)}
+	b.losMutex.Lock()
+	cached, ok := b.losCache[key]
+	b.losMutex.Unlock()
+	if ok && cached.Checked.After(b.clock.Now().Add(-losCacheTTL)) {
+		b.metrics.countSight(true, 0)
+		return cached.Visible
+	}
+
+	visible := true
+	tested := 0
+	half := b.tileSize() / 2
+	var deadline time.Time
+	if b.cfg.WallTTL > 0 {
+		deadline = b.clock.Now().Add(-b.cfg.WallTTL)
+	}
+	b.wallMutex.Lock()
+	for bucket := range bucketsAlong(playerLoc, itemLoc) {
+		for wall, seen := range b.wallIndex[bucket] {
+			// a wall that's decayed but not been swept up yet doesn't count
+			if seen.Before(deadline) {
+				continue
+			}
+			tested++
+			if intersects(playerLoc, itemLoc, wall.X, wall.Y, half) {
+				visible = false
+				break
+			}
+		}
+		if !visible {
+			break
+		}
+	}
+	b.wallMutex.Unlock()
+	b.metrics.countSight(false, tested)
+
+	b.losMutex.Lock()
+	if len(b.losCache) >= losCacheSize {
+		b.trimSightLines()
+	}
+	b.losCache[key] = sighting{Visible: visible, Checked: b.clock.Now()}
+	b.losMutex.Unlock()
+	return visible
+}
+
+// the wall index buckets a location is in.  Buckets are a fixed size in game units, so the index
+// doesn't need rebuilding if we learn a different tile size
+func bucketOf(loc Loc) Loc {
+	return Loc{X: floorDiv(loc.X, losBucket), Y: floorDiv(loc.Y, losBucket)}
+}
+
+// add and remove walls from the index, indexing a wall again when we're told about it again.  Must be called with wallMutex held
+func (b *Bot) indexWall(x int, y int) {
+	bucket := bucketOf(Loc{X: x, Y: y})
+	if b.wallIndex[bucket] == nil {
+		b.wallIndex[bucket] = make(map[Loc]time.Time)
+	}
+	b.wallIndex[bucket][Loc{X: x, Y: y}] = b.clock.Now()
+}
+
+func (b *Bot) unindexWall(x int, y int) {
+	bucket := bucketOf(Loc{X: x, Y: y})
+	delete(b.wallIndex[bucket], Loc{X: x, Y: y})
+	if len(b.wallIndex[bucket]) == 0 {
+		delete(b.wallIndex, bucket)
+	}
+}
+
+// every bucket that could hold a wall in the way of a line: those the line passes through, found by
+// sampling it every half bucket, and their neighbours, since a wall spills over into the next bucket
+// by up to half a tile and the samples can skip a corner the line clips
+func bucketsAlong(from Loc, to Loc) map[Loc]bool {
+	buckets := make(map[Loc]bool)
+	steps := int(math.Ceil(distanceBetween(from, to)/(losBucket/2))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		at := bucketOf(Loc{X: from.X + int(math.Round(t*float64(to.X-from.X))), Y: from.Y + int(math.Round(t*float64(to.Y-from.Y)))})
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				buckets[Loc{X: at.X + dx, Y: at.Y + dy}] = true
+			}
+		}
+	}
+	return buckets
+}
+
+// which tile is a location in?  rounds towards negative infinity so tiles either side of 0 don't merge
+func (b *Bot) cellOf(loc Loc) Loc {
+	tile := b.tileSize()
+	return Loc{X: floorDiv(loc.X, tile), Y: floorDiv(loc.Y, tile)}
+}
+
+func floorDiv(a int, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// a new wall may block cached lines of sight that pass near it, so drop them
+func (b *Bot) forgetSightLinesNear(wallX int, wallY int) {
+	wall := b.cellOf(Loc{X: wallX, Y: wallY})
+	b.losMutex.Lock()
+	defer b.losMutex.Unlock()
+	for line := range b.losCache {
+		// a wall's box can spill into the neighbouring tiles, so pad the line's bounding box by one
+		lo := Loc{X: min(line.From.X, line.To.X) - 1, Y: min(line.From.Y, line.To.Y) - 1}
+		hi := Loc{X: max(line.From.X, line.To.X) + 1, Y: max(line.From.Y, line.To.Y) + 1}
+		if isWithinBounds(wall, lo, hi) {
+			delete(b.losCache, line)
+		}
+	}
+}
+
+// make room in a full cache: drop the lines that have run out anyway, or if that isn't enough, the lot.
+// Keeping it small also keeps down the cost of forgetSightLinesNear, which scans it for every new wall.
+// Must be called with losMutex held
+func (b *Bot) trimSightLines() {
+	stale := b.clock.Now().Add(-losCacheTTL)
+	for line, cached := range b.losCache {
+		if !cached.Checked.After(stale) {
+			delete(b.losCache, line)
+		}
+	}
+	if len(b.losCache) >= losCacheSize*3/4 {
+		b.losCache = make(map[sightLine]sighting)
+	}
+}
+
+func (b *Bot) forgetAllSightLines() {
+	b.losMutex.Lock()
+	defer b.losMutex.Unlock()
+	b.losCache = make(map[sightLine]sighting)
+}
+
+// does a given wall tile (extending half a tile each way from its position) intersect the line between us and the item?
+// The slab method: clip the segment to the range of its length that lies between the box's sides on each axis in turn;
+// it hits the box if anything is left.  Touching an edge or corner counts as a hit
+func intersects(playerLoc Loc, itemLoc Loc, wallX int, wallY int, half int) bool {
+	// work in floats from the start, differences and products of the server's coordinates can overflow ints
+	from := [2]float64{float64(playerLoc.X), float64(playerLoc.Y)}
+	delta := [2]float64{float64(itemLoc.X) - from[0], float64(itemLoc.Y) - from[1]}
+	lo := [2]float64{float64(wallX) - float64(half), float64(wallY) - float64(half)}
+	hi := [2]float64{float64(wallX) + float64(half), float64(wallY) + float64(half)}
+	tMin, tMax := 0.0, 1.0
+	for axis := 0; axis < 2; axis++ {
+		if delta[axis] == 0 {
+			// parallel to this pair of sides, so either always between them or never
+			if from[axis] < lo[axis] || from[axis] > hi[axis] {
+				return false
+			}
+			continue
+		}
+		t1 := (lo[axis] - from[axis]) / delta[axis]
+		t2 := (hi[axis] - from[axis]) / delta[axis]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tMin = math.Max(tMin, t1)
+		tMax = math.Min(tMax, t2)
+		if tMin > tMax {
+			return false
+		}
+	}
+	return true
+}
+
+// is the given point within the bounds created between p1 and p2?
+func isWithinBounds(point Loc, p1 Loc, p2 Loc) bool {
+	minX := math.Min(float64(p1.X), float64(p2.X))
+	maxX := math.Max(float64(p1.X), float64(p2.X))
+	minY := math.Min(float64(p1.Y), float64(p2.Y))
+	maxY := math.Max(float64(p1.Y), float64(p2.Y))
+	return point.X >= int(minX) && point.X <= int(maxX) && point.Y >= int(minY) && point.Y <= int(maxY)
+}
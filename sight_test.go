@@ -0,0 +1,246 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSightCacheBounded(t *testing.T) {
+	b, clock := newTestBot(nil)
+	b.setWall(1000, 1000)
+	for i := 0; i < 3*losCacheSize; i++ {
+		b.canSeeItem(Loc{X: i * b.tileSize()}, Loc{X: i * b.tileSize(), Y: 8})
+		if i%losCacheSize == 0 {
+			clock.Advance(time.Millisecond)
+		}
+	}
+	b.losMutex.Lock()
+	size := len(b.losCache)
+	b.losMutex.Unlock()
+	if size > losCacheSize {
+		t.Fatalf("line of sight cache grew to %d, more than %d", size, losCacheSize)
+	}
+	b.resetRound(false)
+	if len(b.losCache) != 0 {
+		t.Fatal("line of sight cache survived a new round")
+	}
+}
+
+// pairs of floor tiles in a layout within sight range of each other, as we'd check them in play, the same every time
+func sightChecks(l *layout, tile int, n int) [][2]Loc {
+	rng := rand.New(rand.NewSource(1))
+	tiles := l.floorTiles()
+	var checks [][2]Loc
+	for len(checks) < n {
+		from, to := tiles[rng.Intn(len(tiles))], tiles[rng.Intn(len(tiles))]
+		if from != to && distanceBetween(from, to) <= 12 {
+			checks = append(checks, [2]Loc{l.locOf(from, tile), l.locOf(to, tile)})
+		}
+	}
+	return checks
+}
+
+// the benchmarks move the clock on to age out the line of sight cache, which mustn't age out the walls too
+func keepWalls(cfg *Config) {
+	cfg.WallTTL = 0
+}
+
+func BenchmarkCanSeeItem(b *testing.B) {
+	for _, size := range []int{31, 61, 101} {
+		l := maze(size, size, 1)
+		bot, clock := loadLayout(b, l, keepWalls)
+		checks := sightChecks(l, bot.tileSize(), 1000)
+		b.Run(fmt.Sprintf("%d walls", len(l.walls)), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				// a fresh question every time, as if this were the first tick it came up
+				clock.Advance(losCacheTTL)
+				check := checks[i%len(checks)]
+				bot.canSeeItem(check[0], check[1])
+			}
+		})
+		b.Run(fmt.Sprintf("%d walls cached", len(l.walls)), func(b *testing.B) {
+			for _, check := range checks {
+				bot.canSeeItem(check[0], check[1])
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				check := checks[i%len(checks)]
+				bot.canSeeItem(check[0], check[1])
+			}
+		})
+	}
+}
+
+// line of sight the way it was before the wall index: test the line against every wall we know of
+func canSeeByScan(b *Bot, playerLoc Loc, itemLoc Loc) bool {
+	half := b.tileSize() / 2
+	b.wallMutex.Lock()
+	defer b.wallMutex.Unlock()
+	for x, column := range b.state.Walls {
+		for y := range column {
+			if intersects(playerLoc, itemLoc, x, y, half) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestSightIndexMatchesScan(t *testing.T) {
+	l := maze(61, 61, 1)
+	b, clock := loadLayout(t, l, keepWalls)
+	visible := 0
+	for _, check := range sightChecks(l, b.tileSize(), 2000) {
+		clock.Advance(losCacheTTL)
+		indexed, scanned := b.canSeeItem(check[0], check[1]), canSeeByScan(b, check[0], check[1])
+		if indexed != scanned {
+			t.Errorf("%v to %v: index says %v, scanning every wall says %v", check[0], check[1], indexed, scanned)
+		}
+		if indexed {
+			visible++
+		}
+	}
+	if visible == 0 || visible == 2000 {
+		t.Errorf("%d of 2000 checks visible, the maze isn't testing anything", visible)
+	}
+}
+
+func TestIntersectsDiagonal(t *testing.T) {
+	const far = 1 << 62
+	tests := []struct {
+		name         string
+		from, to     Loc
+		wallX, wallY int
+		want         bool
+	}{
+		{"wall on the line", Loc{X: 0, Y: 0}, Loc{X: 64, Y: 64}, 32, 32, true},
+		{"wall on the line going back", Loc{X: 64, Y: 64}, Loc{X: 0, Y: 0}, 32, 32, true},
+		{"corner touching the line", Loc{X: 0, Y: 0}, Loc{X: 64, Y: 64}, 40, 24, true},
+		{"just clear of the line", Loc{X: 0, Y: 0}, Loc{X: 64, Y: 64}, 41, 24, false},
+		{"on the line beyond the item", Loc{X: 0, Y: 0}, Loc{X: 64, Y: 64}, 80, 80, false},
+		{"other diagonal", Loc{X: 0, Y: 64}, Loc{X: 64, Y: 0}, 32, 32, true},
+		{"other diagonal, wall off it", Loc{X: 0, Y: 64}, Loc{X: 64, Y: 0}, 16, 16, false},
+		// the difference between the ends doesn't fit in an int
+		{"ends far apart", Loc{X: -far, Y: -far}, Loc{X: far, Y: far}, 0, 0, true},
+		{"ends far apart, wall off the line", Loc{X: -far, Y: -far}, Loc{X: far, Y: far}, 0, 1 << 40, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := intersects(tt.from, tt.to, tt.wallX, tt.wallY, 8); got != tt.want {
+				t.Errorf("intersects(%v, %v, %d, %d) = %v, want %v", tt.from, tt.to, tt.wallX, tt.wallY, got, tt.want)
+			}
+		})
+	}
+}
+
+// the wall index against checking every wall, on a maze of 5202 walls
+func BenchmarkLineOfSight(b *testing.B) {
+	l := maze(101, 101, 1)
+	bot, clock := loadLayout(b, l, keepWalls)
+	checks := sightChecks(l, bot.tileSize(), 1000)
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			check := checks[i%len(checks)]
+			canSeeByScan(bot, check[0], check[1])
+		}
+	})
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			clock.Advance(losCacheTTL)
+			check := checks[i%len(checks)]
+			bot.canSeeItem(check[0], check[1])
+		}
+	})
+}
+
+// the line of sight work in one tick of play: chasing ammo and food round a maze with an enemy in view.
+// The clock moves past losCacheTTL between ticks, so only checks repeated within a tick hit the cache
+func BenchmarkTickSight(b *testing.B) {
+	l := maze(21, 41, 1)
+	bot, clock := loadLayout(b, l, keepWalls)
+	var enemy string
+	for _, t := range []Loc{{X: 3, Y: 1}, {X: 1, Y: 3}} {
+		if mid := (Loc{X: (t.X + 1) / 2, Y: (t.Y + 1) / 2}); l.floor[t] && l.floor[mid] {
+			loc := l.locOf(t, bot.tileSize())
+			enemy = fmt.Sprintf("nearbyplayer:orc,grunt,%d,%d", loc.X, loc.Y)
+		}
+	}
+	if enemy == "" {
+		b.Fatal("nowhere in sight of the start to put the enemy")
+	}
+	ts := &tickState{dir: "ne", target: "key", shooter: NewShooter(0)}
+	m := bot.metrics
+	m.mu.Lock()
+	checks, hits, walls := m.sightChecks, m.sightHits, m.wallTests
+	m.mu.Unlock()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		clock.Advance(losCacheTTL)
+		bot.handleMessage(enemy)
+		bot.tick(nopSender{}, ts)
+	}
+	b.StopTimer()
+	m.mu.Lock()
+	checks, hits, walls = m.sightChecks-checks, m.sightHits-hits, m.wallTests-walls
+	m.mu.Unlock()
+	b.ReportMetric(float64(checks)/float64(b.N), "checks/tick")
+	b.ReportMetric(float64(hits)/float64(b.N), "hits/tick")
+	b.ReportMetric(float64(walls)/float64(b.N), "wall-tests/tick")
+	b.ReportMetric(float64(len(l.walls)), "walls")
+}
+
+func TestFloorDiv(t *testing.T) {
+	tests := []struct {
+		a, b int
+		want int
+	}{
+		{0, 8, 0},
+		{7, 8, 0},
+		{8, 8, 1},
+		{-1, 8, -1}, // not 0, as Go's division would have it
+		{-7, 8, -1},
+		{-8, 8, -1},
+		{-9, 8, -2},
+		{maxCoord, 32, 31250},
+		{-maxCoord, 32, -31250},
+		{-maxCoord - 1, 32, -31251},
+		{math.MaxInt, 8, math.MaxInt / 8},
+		{math.MinInt, 8, math.MinInt / 8},
+		{math.MinInt + 1, 8, math.MinInt / 8},
+	}
+	for _, tt := range tests {
+		if got := floorDiv(tt.a, tt.b); got != tt.want {
+			t.Errorf("floorDiv(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCellOf(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.TileSize = 32 })
+	tests := []struct {
+		loc  Loc
+		want Loc
+	}{
+		{Loc{X: 0, Y: 0}, Loc{X: 0, Y: 0}},
+		{Loc{X: 31, Y: 32}, Loc{X: 0, Y: 1}},
+		{Loc{X: -1, Y: -1}, Loc{X: -1, Y: -1}},
+		{Loc{X: -32, Y: -33}, Loc{X: -1, Y: -2}},
+		{Loc{X: -maxCoord, Y: maxCoord}, Loc{X: -31250, Y: 31250}},
+	}
+	for _, tt := range tests {
+		if got := b.cellOf(tt.loc); got != tt.want {
+			t.Errorf("cellOf(%v) = %v, want %v", tt.loc, got, tt.want)
+		}
+	}
+	// and a wall either side of the origin blocks the line it sits on, whichever side that is
+	for _, x := range []int{-1, -32, -maxCoord + 64} {
+		b.setWall(x, 0)
+		from, to := Loc{X: x - 64, Y: 0}, Loc{X: x + 64, Y: 0}
+		if b.canSeeItem(from, to) {
+			t.Errorf("saw from %v to %v through the wall at (%d,0)", from, to, x)
+		}
+	}
+}
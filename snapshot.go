@@ -0,0 +1,79 @@
+package gauntletbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Snapshot is a consistent copy of what a bot knows at one moment.  It's what a Strategy decides from
+// each tick, and what SIGUSR1 dumps to a file while debugging
+type Snapshot struct {
+	Taken   time.Time
+	Name    string
+	Player  Player
+	Updated time.Time
+	Walls   int
+	Floors  int
+	Items   map[string]int  // how many of each type we know of
+	Enemies map[string]Item // the last sighting of each enemy, by name
+}
+
+// Snapshot copies out the game state, holding every lock it's spread across at once so the read loop can't
+// change one part while we're copying another.  Locks are taken in a fixed order, walls first as
+// everywhere else, and nothing that holds one of them waits for another outside that order
+func (b *Bot) Snapshot() Snapshot {
+	b.wallMutex.Lock()
+	defer b.wallMutex.Unlock()
+	b.floorMutex.Lock()
+	defer b.floorMutex.Unlock()
+	b.itemMutex.Lock()
+	defer b.itemMutex.Unlock()
+	b.enemyMutex.Lock()
+	defer b.enemyMutex.Unlock()
+	b.playerMutex.Lock()
+	defer b.playerMutex.Unlock()
+	s := Snapshot{
+		Taken:   b.clock.Now(),
+		Name:    b.cfg.Name,
+		Player:  b.state.Player,
+		Updated: b.state.Updated,
+		Items:   make(map[string]int),
+		Enemies: make(map[string]Item),
+	}
+	for _, column := range b.state.Walls {
+		s.Walls += len(column)
+	}
+	for _, column := range b.state.Floor {
+		s.Floors += len(column)
+	}
+	for itemType, items := range b.state.Items {
+		s.Items[itemType] = len(items)
+	}
+	for name, enemy := range b.state.Enemies {
+		s.Enemies[name] = enemy
+	}
+	return s
+}
+
+// the snapshot for this tick, taken the first time something asks for one so ticks that don't need
+// it don't pay for copying everything.  Write loop only
+func (b *Bot) tickSnapshot() Snapshot {
+	if b.tickSnap == nil {
+		s := b.Snapshot()
+		b.tickSnap = &s
+	}
+	return *b.tickSnap
+}
+
+// write a snapshot to a timestamped JSON file in the working directory, returning its name
+func (b *Bot) dumpSnapshot() (string, error) {
+	s := b.Snapshot()
+	path := fmt.Sprintf("gauntletbot-%s-%s.json", s.Name, s.Taken.Format("20060102-150405.000"))
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(data, '\n'), 0644)
+}
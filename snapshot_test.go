@@ -0,0 +1,67 @@
+package gauntletbot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// run under -race: a snapshot can be dumped while the read and write loops are busy
+func TestDumpSnapshotRaceFree(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	b, clock := newTestBot(func(cfg *Config) { cfg.Name = "warrior" })
+	read, write := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(read)
+		for i := 0; i < 100; i++ {
+			b.handleMessage("playerupdate:10,10,3,5,False")
+			b.handleMessage("nearbywalls:0,0,8,0")
+			b.handleMessage("nearbyitem:ammo,50,50")
+			b.handleMessage("nearbyplayer:orc,grunt,40,10")
+			b.handleMessage("roundstart")
+		}
+	}()
+	go func() {
+		defer close(write)
+		ts := &tickState{dir: "ne", target: "key", shooter: NewShooter(0)}
+		for i := 0; i < 100; i++ {
+			b.tick(nopSender{}, ts)
+		}
+	}()
+	var paths []string
+	for i := 0; i < 20; i++ {
+		clock.Advance(time.Millisecond) // so each goes to a file of its own
+		path, err := b.dumpSnapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	<-read
+	<-write
+
+	for _, path := range paths {
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s Snapshot
+		if err := json.Unmarshal(data, &s); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if s.Name != "warrior" {
+			t.Errorf("%s is a snapshot of %q, want warrior", path, s.Name)
+		}
+	}
+}
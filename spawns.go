@@ -0,0 +1,47 @@
+package gauntletbot
+
+import (
+	"time"
+)
+
+// remember where an item has turned up, for RememberSpawns.  Must be called with itemMutex held
+func (b *Bot) noteSpawn(itemType string, loc Loc) {
+	if !b.cfg.RememberSpawns {
+		return
+	}
+	if b.state.Spawns[itemType] == nil {
+		b.state.Spawns[itemType] = make(map[Loc]time.Time)
+	}
+	b.state.Spawns[itemType][loc] = b.clock.Now()
+}
+
+// with none of an item in sight, which spot it's turned up at before should we go and look at?  The one
+// we checked longest ago, nearest first among those we've never checked, so we make the rounds of them all.
+// Only touched by the write loop, apart from the spawn points themselves
+func (b *Bot) spawnTarget(here Loc, itemType string) (Loc, bool) {
+	if !b.cfg.RememberSpawns {
+		return Loc{}, false
+	}
+	b.itemMutex.Lock()
+	spawns := make([]Loc, 0, len(b.state.Spawns[itemType]))
+	for loc := range b.state.Spawns[itemType] {
+		spawns = append(spawns, loc)
+	}
+	b.itemMutex.Unlock()
+	reach := float64(b.tileSize())
+	var best Loc
+	found := false
+	for _, loc := range spawns {
+		if distanceBetween(here, loc) <= reach {
+			// we're here and it isn't, so it's checked
+			b.spawnChecked[loc] = b.clock.Now()
+			continue
+		}
+		if !found || b.spawnChecked[loc].Before(b.spawnChecked[best]) ||
+			(b.spawnChecked[loc].Equal(b.spawnChecked[best]) && distanceBetween(here, loc) < distanceBetween(here, best)) {
+			best = loc
+			found = true
+		}
+	}
+	return best, found
+}
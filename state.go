@@ -0,0 +1,364 @@
+package gauntletbot
+
+import (
+	"time"
+)
+
+// Game state structures
+type State struct {
+	Player      Player
+	Updated     time.Time // when we last had a playerupdate
+	Motion      Motion
+	Floor       map[int]map[int]bool         // x:y:floor
+	Walls       map[int]map[int]bool         // x:y:wall
+	Enemies     map[string]Item              // the last sighting of each enemy, by name
+	Teammates   map[string]Item              // last sighting of each friendly player
+	Items       map[string][]Item            // everything nearbyitem has told us about, other than our key, by type
+	Spawns      map[string]map[Loc]time.Time // where items of each type have turned up and when we last saw one there, for RememberSpawns
+	Override    *Override                    // an objective imposed from outside, which beats our own judgement while it lasts
+	Interrupted *Item                        // an item we were on our way to when a fight got in the way, to come back to afterwards, under goalMutex
+}
+
+// Override is an objective set by an external controller
+type Override struct {
+	Goal  string // "goto" to head for Loc, or "fight" to go after the enemy
+	Loc   Loc
+	Until time.Time
+}
+
+type Player struct {
+	Name   string // as the server knows us, which may differ from the name we asked for
+	ID     string // anything else the server identified us by in playerjoined
+	Loc    Loc
+	Health int
+	Ammo   int
+	HasKey bool
+	Exit   *Loc // where the exit is and where our key is, once we've seen them.  Replaced, never changed in place
+	MyKey  *Loc
+}
+
+// what we've learnt about how we move, so we can guess where we'll be by the time the server acts
+type Motion struct {
+	Latency  time.Duration // how long the server takes to start acting on a moveto
+	VX       float64       // our velocity, in units per second
+	VY       float64
+	moveSent time.Time // when we sent the moveto we're waiting to see take effect
+	moveFrom Loc
+	lastLoc  Loc
+	lastSeen time.Time
+}
+
+type Loc struct {
+	X int
+	Y int
+}
+
+type Item struct {
+	Type   string // for players, their name
+	Loc    Loc
+	Seen   time.Time
+	Facing string // which way a player was facing, if the server tells us
+	Health int    // for players, their health if the server tells us, otherwise -1
+}
+
+// Global variables
+var compass = []string{"n", "ne", "e", "se", "s", "sw", "w", "nw"}
+
+const cautionRadius = 5                           // how many tiles around us to consider when measuring local coverage
+const exitLockWait = 2 * time.Second              // how long standing on the exit without being let out means it's locked
+const sightingDedupWindow = 50 * time.Millisecond // the same enemy at the same spot within this is a repeat, not a new sighting
+const maxCoord = 1e6                              // no sane arena has positions further out than this
+const losBucket = 32                              // size in game units of the buckets walls are indexed by for line of sight checks
+const minTickMs = 10                              // the shortest tick we'll run at, however we're configured
+const losCacheTTL = 500 * time.Millisecond        // how long a line of sight result stays good for if no walls turn up near it
+const losCacheSize = 4096                         // the most line of sight results we keep
+const exploreRadius = 20                          // how many tiles around us to consider when looking for unexplored space
+const bounceSensitivity = 1                       // how far we must have moved along an axis for it not to count as blocked
+const itemDedupRadius = 2                         // items of the same type reported this close together are the same one
+const itemTTL = 5 * time.Second                   // how long items other than ammo and food stay known without being seen again
+const readBufferSize = 1024                       // the most we read from the server at once
+
+// clear everything that only makes sense within a single round
+func (b *Bot) resetRound(clearMap bool) {
+	b.itemMutex.Lock()
+	b.state.Items = make(map[string][]Item)
+	b.itemMutex.Unlock()
+	b.enemyMutex.Lock()
+	b.state.Enemies = make(map[string]Item)
+	b.enemyMutex.Unlock()
+	b.motionMutex.Lock()
+	b.sightings = make(map[string]*sightingHistory)
+	b.motionMutex.Unlock()
+	if clearMap {
+		b.wallMutex.Lock()
+		b.floorMutex.Lock()
+		b.state.Walls = make(map[int]map[int]bool)
+		b.wallIndex = make(map[Loc]map[Loc]time.Time)
+		b.state.Floor = make(map[int]map[int]bool)
+		b.cellSeen = make(map[Loc]time.Time)
+		b.floorMutex.Unlock()
+		b.wallMutex.Unlock()
+		// a new map means new places for items to turn up
+		b.itemMutex.Lock()
+		b.state.Spawns = make(map[string]map[Loc]time.Time)
+		b.itemMutex.Unlock()
+	}
+	// and start each round with an empty line of sight cache, whether or not the map's the same
+	b.forgetAllSightLines()
+	// nothing we were heading for last round is still there
+	b.goalMutex.Lock()
+	b.state.Interrupted = nil
+	b.headingFor = nil
+	b.goalMutex.Unlock()
+}
+
+// a new round means a new key to find and a new exit to reach
+func (b *Bot) resetObjective() {
+	b.playerMutex.Lock()
+	b.state.Player.MyKey = nil
+	b.state.Player.Exit = nil
+	b.state.Player.HasKey = false
+	b.playerMutex.Unlock()
+	// so we stop reporting how far we were from last round's exit
+	b.metrics.setExitDistance(0, false)
+	// and a fresh go at finding it before we give up
+	b.goalMutex.Lock()
+	b.keySince = time.Time{}
+	b.keyGivenUp = false
+	b.goalMutex.Unlock()
+}
+
+// Threadsafe setters to allow the readloop to set these values while forcing the writeloop to wait to read them.
+// A tile is never both wall and floor: whichever we were told about most recently wins.
+// Both take the wall lock before the floor lock, as must anything else that holds both
+func (b *Bot) setWall(x int, y int) {
+	b.wallMutex.Lock()
+	b.floorMutex.Lock()
+	_, ok := b.state.Walls[x]
+	if !ok {
+		b.state.Walls[x] = make(map[int]bool)
+	}
+	isNew := !b.state.Walls[x][y]
+	b.state.Walls[x][y] = true
+	b.indexWall(x, y)
+	delete(b.state.Floor[x], y)
+	evicted := b.noteCell(x, y)
+	b.floorMutex.Unlock()
+	b.wallMutex.Unlock()
+	if evicted {
+		b.forgetAllSightLines()
+	} else if isNew {
+		b.forgetSightLinesNear(x, y)
+	}
+}
+
+func (b *Bot) setFloor(x int, y int) {
+	b.wallMutex.Lock()
+	b.floorMutex.Lock()
+	_, ok := b.state.Floor[x]
+	if !ok {
+		b.state.Floor[x] = make(map[int]bool)
+	}
+	b.state.Floor[x][y] = true
+	wasWall := b.state.Walls[x][y]
+	delete(b.state.Walls[x], y)
+	b.unindexWall(x, y)
+	evicted := b.noteCell(x, y)
+	b.floorMutex.Unlock()
+	b.wallMutex.Unlock()
+	if evicted {
+		b.forgetAllSightLines()
+	} else if wasWall {
+		// the wall we thought was here may have been all that blocked some of our cached sight lines
+		b.forgetSightLinesNear(x, y)
+	}
+}
+
+func (b *Bot) setTeammate(name string, x int, y int) {
+	b.teamMutex.Lock()
+	defer b.teamMutex.Unlock()
+	b.state.Teammates[name] = Item{Loc: Loc{X: x, Y: y}, Seen: b.clock.Now()}
+}
+
+// the server reports the same items over and over, so an item at (or within itemDedupRadius of) one we
+// already know about is that one seen again: refresh it rather than adding a duplicate
+func (b *Bot) addItem(itemType string, x int, y int) {
+	b.itemMutex.Lock()
+	defer b.itemMutex.Unlock()
+	loc := Loc{X: x, Y: y}
+	items := b.state.Items[itemType]
+	for i := range items {
+		if distanceBetween(items[i].Loc, loc) <= itemDedupRadius {
+			items[i].Loc = loc
+			items[i].Seen = b.clock.Now()
+			b.noteSpawn(itemType, loc)
+			return
+		}
+	}
+	b.noteSpawn(itemType, loc)
+	items = append(items, Item{Type: itemType, Loc: loc, Seen: b.clock.Now()})
+	b.state.Items[itemType] = items
+}
+
+func (b *Bot) addFood(x int, y int) {
+	b.addItem("food", x, y)
+}
+
+func (b *Bot) addAmmo(x int, y int) {
+	b.addItem("ammo", x, y)
+}
+
+// an enemy sighting as the write loop sees it, copied out from under the lock
+type enemySighting struct {
+	Loc    *Loc // nil if there's nobody
+	Seen   time.Time
+	Dir    string
+	Name   string
+	Health int // -1 if we don't know
+}
+
+// the enemy to deal with: the nearest of those seen in the last second, or if there are none
+// of those, whoever we saw most recently
+func (b *Bot) snapshotEnemy() enemySighting {
+	here := b.snapshotPlayer().Loc
+	recent := b.clock.Now().Add(-1 * time.Second)
+	b.enemyMutex.Lock()
+	defer b.enemyMutex.Unlock()
+	var best *Item
+	for name := range b.state.Enemies {
+		e := b.state.Enemies[name]
+		if best == nil {
+			best = &e
+			continue
+		}
+		live, bestLive := e.Seen.After(recent), best.Seen.After(recent)
+		if (live && !bestLive) ||
+			(live && bestLive && distanceBetween(here, e.Loc) < distanceBetween(here, best.Loc)) ||
+			(!live && !bestLive && e.Seen.After(best.Seen)) {
+			best = &e
+		}
+	}
+	if best == nil {
+		return enemySighting{}
+	}
+	return enemySighting{Loc: &best.Loc, Seen: best.Seen, Dir: best.Facing, Name: best.Type, Health: best.Health}
+}
+
+// forget enemies we haven't seen for EnemyTTL: they've died or moved on, and chasing where they were is pointless
+func (b *Bot) expireEnemy() {
+	if b.cfg.EnemyTTL == 0 {
+		return
+	}
+	deadline := b.clock.Now().Add(-b.cfg.EnemyTTL)
+	b.enemyMutex.Lock()
+	defer b.enemyMutex.Unlock()
+	for name, e := range b.state.Enemies {
+		if e.Seen.Before(deadline) {
+			delete(b.state.Enemies, name)
+			b.metrics.noteGone(name, e.Seen)
+		}
+	}
+}
+
+// forget walls we haven't been told about for WallTTL, so one that's been destroyed (or that we misread)
+// doesn't block our sight and paths forever
+func (b *Bot) expireWalls() {
+	if b.cfg.WallTTL == 0 {
+		return
+	}
+	deadline := b.clock.Now().Add(-b.cfg.WallTTL)
+	expired := 0
+	b.wallMutex.Lock()
+	b.floorMutex.Lock() // for cellSeen
+	for _, walls := range b.wallIndex {
+		for wall, seen := range walls {
+			if seen.Before(deadline) {
+				delete(b.state.Walls[wall.X], wall.Y)
+				if len(b.state.Walls[wall.X]) == 0 {
+					delete(b.state.Walls, wall.X)
+				}
+				b.unindexWall(wall.X, wall.Y)
+				delete(b.cellSeen, wall)
+				expired++
+			}
+		}
+	}
+	b.floorMutex.Unlock()
+	b.wallMutex.Unlock()
+	if expired > 0 {
+		debugf("Forgot %d walls we haven't seen for %s", expired, b.cfg.WallTTL)
+		b.forgetAllSightLines()
+	}
+}
+
+// forget walls further than WallRadius from here.  We'll have to learn them again if we come back,
+// but on a big map that's better than checking every sight line against walls we left long ago
+func (b *Bot) pruneWalls(here Loc) {
+	if b.cfg.WallRadius == 0 {
+		return
+	}
+	radius := float64(b.cfg.WallRadius)
+	pruned := 0
+	b.wallMutex.Lock()
+	b.floorMutex.Lock() // for cellSeen
+	for x, column := range b.state.Walls {
+		for y := range column {
+			if distanceBetween(here, Loc{X: x, Y: y}) <= radius {
+				continue
+			}
+			delete(column, y)
+			b.unindexWall(x, y)
+			delete(b.cellSeen, Loc{X: x, Y: y})
+			pruned++
+		}
+		if len(column) == 0 {
+			delete(b.state.Walls, x)
+		}
+	}
+	b.floorMutex.Unlock()
+	b.wallMutex.Unlock()
+	if pruned > 0 {
+		debugf("Forgot %d walls more than %d away", pruned, b.cfg.WallRadius)
+		b.forgetAllSightLines()
+	}
+}
+
+// how long an item of a type stays known without being seen again.  0 is for ever
+func (b *Bot) itemTTL(itemType string) time.Duration {
+	switch itemType {
+	case "ammo":
+		return b.cfg.AmmoTTL
+	case "food":
+		return b.cfg.FoodTTL
+	}
+	return itemTTL
+}
+
+// a copy of the items of one type we currently know about, safe to use without holding the lock
+func (b *Bot) itemsOf(itemType string) []Item {
+	b.itemMutex.Lock()
+	defer b.itemMutex.Unlock()
+	return append([]Item(nil), b.state.Items[itemType]...)
+}
+
+// items may have been picked up but the game doesn't tell us
+// delete any items that we haven't seen within their type's TTL: AmmoTTL, FoodTTL or itemTTL for anything else
+func (b *Bot) expireItems() {
+	b.itemMutex.Lock()
+	defer b.itemMutex.Unlock()
+	for itemType, items := range b.state.Items {
+		ttl := b.itemTTL(itemType)
+		if ttl == 0 {
+			continue
+		}
+		deadline := b.clock.Now().Add(-ttl)
+		fresh := make([]Item, 0)
+		for _, item := range items {
+			if item.Seen.After(deadline) {
+				// seen recently enough, keep it
+				fresh = append(fresh, item)
+			}
+		}
+		b.state.Items[itemType] = fresh
+	}
+}
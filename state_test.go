@@ -0,0 +1,284 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestBot(configure func(*Config)) (*Bot, *FakeClock) {
+	clock := NewFakeClock(time.Date(2022, 2, 19, 12, 0, 0, 0, time.UTC))
+	cfg := DefaultConfig()
+	cfg.Clock = clock
+	if configure != nil {
+		configure(&cfg)
+	}
+	return NewBot(cfg), clock
+}
+
+func TestItemsExpire(t *testing.T) {
+	for _, itemType := range []string{"ammo", "food"} {
+		b, clock := newTestBot(func(cfg *Config) {
+			cfg.AmmoTTL = 5 * time.Second
+			cfg.FoodTTL = 5 * time.Second
+		})
+		b.addItem(itemType, 10, 10)
+		clock.Advance(4 * time.Second)
+		b.expireItems()
+		if len(b.itemsOf(itemType)) != 1 {
+			t.Fatalf("%s forgotten before its TTL", itemType)
+		}
+		// seeing it again starts the TTL over
+		b.addItem(itemType, 10, 10)
+		clock.Advance(4 * time.Second)
+		b.expireItems()
+		if len(b.itemsOf(itemType)) != 1 {
+			t.Fatalf("%s forgotten though we saw it again", itemType)
+		}
+		clock.Advance(2 * time.Second)
+		b.expireItems()
+		if len(b.itemsOf(itemType)) != 0 {
+			t.Fatalf("%s remembered past its TTL", itemType)
+		}
+	}
+}
+
+func TestFoodTTLIndependent(t *testing.T) {
+	b, clock := newTestBot(func(cfg *Config) {
+		cfg.AmmoTTL = 2 * time.Second
+		cfg.FoodTTL = 10 * time.Second
+	})
+	b.addItem("ammo", 10, 10)
+	b.addItem("food", 20, 20)
+	clock.Advance(5 * time.Second)
+	b.expireItems()
+	if len(b.itemsOf("ammo")) != 0 || len(b.itemsOf("food")) != 1 {
+		t.Errorf("after 5s know of %d ammo and %d food, want 0 and 1", len(b.itemsOf("ammo")), len(b.itemsOf("food")))
+	}
+	clock.Advance(6 * time.Second)
+	b.expireItems()
+	if len(b.itemsOf("food")) != 0 {
+		t.Error("food remembered past FoodTTL")
+	}
+}
+
+func TestRoundReset(t *testing.T) {
+	for _, resetMap := range []bool{false, true} {
+		b, _ := newTestBot(func(cfg *Config) { cfg.ResetMapOnRound = resetMap })
+		for _, msg := range []string{
+			"playerupdate:0,0,5,3,True",
+			"nearbywalls:0,0,8,0",
+			"nearbyitem:ammo,8,8",
+			"nearbyitem:" + b.myKeyName() + ",16,16",
+			"nearbyplayer:orc,grunt,24,24",
+			"exit:40,60",
+		} {
+			b.handleMessage(msg)
+		}
+		// on our way back to the ammo after a fight
+		b.state.Interrupted = &Item{Type: "ammo", Loc: Loc{X: 8, Y: 8}}
+		b.headingFor = b.state.Interrupted
+
+		b.handleMessage("roundend")
+		if b.state.Interrupted != nil || b.headingFor != nil {
+			t.Errorf("resetMap %v: still heading for %v, interrupted on the way to %v, after the round ended", resetMap, b.headingFor, b.state.Interrupted)
+		}
+		s := b.Snapshot()
+		if len(s.Items) != 0 || len(s.Enemies) != 0 {
+			t.Errorf("resetMap %v: still know of items %v and enemies %v after the round ended", resetMap, s.Items, s.Enemies)
+		}
+		wantWalls := 2
+		if resetMap {
+			wantWalls = 0
+		}
+		if s.Walls != wantWalls {
+			t.Errorf("resetMap %v: know of %d walls after the round ended, want %d", resetMap, s.Walls, wantWalls)
+		}
+		if s.Player.Exit == nil || s.Player.MyKey == nil {
+			t.Errorf("resetMap %v: forgot the objective before the next round started", resetMap)
+		}
+
+		b.handleMessage("roundstart")
+		p := b.snapshotPlayer()
+		if p.Exit != nil || p.MyKey != nil || p.HasKey {
+			t.Errorf("resetMap %v: objective not reset for the new round: %+v", resetMap, p)
+		}
+	}
+}
+
+func TestExitDistanceForgotten(t *testing.T) {
+	b, _ := newTestBot(nil)
+	b.handleMessage("playerupdate:0,0,5,3,False")
+	b.handleMessage("exit:30,40")
+	p := b.snapshotPlayer()
+	b.metrics.setExitDistance(distanceBetween(p.Loc, *p.Exit), true)
+	var out strings.Builder
+	b.writeMetrics(&out)
+	if !strings.Contains(out.String(), "gauntletbot_exit_distance 50\n") {
+		t.Fatalf("no exit distance of 50 in\n%s", out.String())
+	}
+
+	b.handleMessage("roundstart")
+	out.Reset()
+	b.writeMetrics(&out)
+	if strings.Contains(out.String(), "exit_distance") {
+		t.Errorf("still reporting last round's exit distance in\n%s", out.String())
+	}
+}
+
+func TestEnemiesExpire(t *testing.T) {
+	b, clock := newTestBot(func(cfg *Config) { cfg.EnemyTTL = 3 * time.Second })
+	b.handleMessage("nearbyplayer:orc,grunt,24,24")
+	clock.Advance(2 * time.Second)
+	b.expireEnemy()
+	if e := b.snapshotEnemy(); e.Loc == nil || e.Name != "orc" {
+		t.Fatalf("forgot an enemy seen 2s ago: %+v", e)
+	}
+	clock.Advance(8 * time.Second)
+	b.expireEnemy()
+	if e := b.snapshotEnemy(); e.Loc != nil {
+		t.Errorf("still chasing an enemy seen 10s ago: %+v", e)
+	}
+	if n := len(b.Snapshot().Enemies); n != 0 {
+		t.Errorf("still know of %d enemies", n)
+	}
+}
+
+// how much of the heap is in use once the garbage's been collected
+func heapInUse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// what a wall radius saves on a big map: the walls and heap we keep once the far walls have gone,
+// and what line of sight checks around us cost
+func BenchmarkWallRadius(b *testing.B) {
+	l := maze(201, 201, 1)
+	centre := Loc{X: l.w / 2, Y: l.h / 2}
+	for _, radius := range []int{0, 40, 20} {
+		name := "unlimited"
+		if radius > 0 {
+			name = fmt.Sprintf("%d tiles", radius)
+		}
+		b.Run(name, func(b *testing.B) {
+			before := heapInUse()
+			cfg := func(cfg *Config) {
+				keepWalls(cfg)
+				cfg.WallRadius = radius * cfg.TileSize
+			}
+			bot, clock := loadLayout(b, l, cfg)
+			here := l.locOf(centre, bot.tileSize())
+			bot.pruneWalls(here)
+			heap, walls := int64(heapInUse())-int64(before), bot.Snapshot().Walls
+
+			var checks [][2]Loc
+			for _, check := range sightChecks(l, bot.tileSize(), 20000) {
+				if distanceBetween(check[0], here) < float64(8*bot.tileSize()) {
+					checks = append(checks, check)
+				}
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				clock.Advance(losCacheTTL)
+				check := checks[i%len(checks)]
+				bot.canSeeItem(check[0], check[1])
+			}
+			b.ReportMetric(float64(heap), "heap-bytes")
+			b.ReportMetric(float64(walls), "walls")
+		})
+	}
+}
+
+// what pruning costs each tick once the far walls have gone
+func BenchmarkPruneWalls(b *testing.B) {
+	l := maze(201, 201, 1)
+	bot, _ := loadLayout(b, l, func(cfg *Config) { cfg.WallRadius = 20 * cfg.TileSize })
+	here := l.locOf(Loc{X: l.w / 2, Y: l.h / 2}, bot.tileSize())
+	bot.pruneWalls(here)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bot.pruneWalls(here)
+	}
+}
+
+func TestWallFloorConflict(t *testing.T) {
+	b, clock := newTestBot(func(cfg *Config) { cfg.TileSize = 8 })
+	from, to := Loc{X: 4, Y: 20}, Loc{X: 36, Y: 20}
+	isWall := func() bool {
+		b.wallMutex.Lock()
+		defer b.wallMutex.Unlock()
+		return b.state.Walls[20][20]
+	}
+	isFloor := func() bool {
+		b.floorMutex.Lock()
+		defer b.floorMutex.Unlock()
+		return b.state.Floor[20][20]
+	}
+	// whichever the server said last wins, and line of sight follows it
+	steps := []struct {
+		msg  string
+		wall bool
+	}{
+		{"nearbywalls:20,20", true},
+		{"nearbyfloors:20,20", false},
+		{"nearbywalls:20,20", true},
+		{"nearbyfloors:20,20", false},
+	}
+	for _, step := range steps {
+		b.handleMessage(step.msg)
+		if isWall() != step.wall || isFloor() == step.wall {
+			t.Fatalf("after %s: wall %v and floor %v, want wall %v and floor %v", step.msg, isWall(), isFloor(), step.wall, !step.wall)
+		}
+		clock.Advance(losCacheTTL / 2) // well within the sight cache
+		if seen := b.canSeeItem(from, to); seen == step.wall {
+			t.Errorf("after %s: can see past it %v", step.msg, seen)
+		}
+	}
+	checkMapConsistent(t, b)
+}
+
+func TestItemTTLEdges(t *testing.T) {
+	tests := []struct {
+		name     string
+		itemType string
+		ammoTTL  time.Duration
+		age      time.Duration
+		kept     bool
+	}{
+		{"just inside", "ammo", 5 * time.Second, 5*time.Second - time.Millisecond, true},
+		{"exactly the ttl", "ammo", 5 * time.Second, 5 * time.Second, false},
+		{"ttl 0 keeps it for good", "ammo", 0, time.Hour, true},
+		{"other types go by itemTTL", "scroll", time.Hour, itemTTL, false},
+		{"other types ignore AmmoTTL", "scroll", time.Millisecond, itemTTL - time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		b, clock := newTestBot(func(cfg *Config) { cfg.AmmoTTL = tt.ammoTTL })
+		b.addItem(tt.itemType, 10, 10)
+		clock.Advance(tt.age)
+		b.expireItems()
+		if kept := len(b.itemsOf(tt.itemType)) == 1; kept != tt.kept {
+			t.Errorf("%s: %s kept after %s: %v, want %v", tt.name, tt.itemType, tt.age, kept, tt.kept)
+		}
+	}
+
+	// each item goes on its own schedule, not with the rest of its type
+	b, clock := newTestBot(func(cfg *Config) { cfg.AmmoTTL = 5 * time.Second })
+	for i := 0; i < 5; i++ {
+		b.addItem("ammo", 100*i, 0)
+		clock.Advance(time.Second)
+	}
+	b.expireItems()
+	if got := len(b.itemsOf("ammo")); got != 4 {
+		t.Errorf("%d of 5 ammo seen a second apart kept, want the newest 4", got)
+	}
+
+	cfg := DefaultConfig()
+	cfg.FoodTTL = -time.Second
+	if cfg.Validate() == nil {
+		t.Error("accepted a negative food ttl")
+	}
+}
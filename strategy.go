@@ -0,0 +1,143 @@
+package gauntletbot
+
+import (
+	"math"
+	"time"
+)
+
+// Action is what a Strategy wants done this tick.  Goal is one of the targets the write loop knows how
+// to pursue: key, exit, ammo, food, enemy, flee, kite, evade, dodge, strafe, defend, warmup, or goto to head for Loc
+type Action struct {
+	Goal string
+	Loc  Loc // where to go, for goto
+}
+
+// Strategy decides what to go after each tick from a snapshot of the game.  The write loop carries
+// the action out and does the talking to the server, so a strategy needn't know about either
+type Strategy interface {
+	Decide(s Snapshot) Action
+}
+
+// DefaultStrategy is the bot's own judgement: a whole ladder of overrides, scripts, supply runs and
+// combat profiles, checked in order until one of them says what to go after.  Beyond the snapshot's
+// player it reads the rest of the bot's state as it goes
+type DefaultStrategy struct {
+	bot *Bot
+}
+
+func (d DefaultStrategy) Decide(s Snapshot) Action {
+	b, p := d.bot, s.Player
+	b.updateSupplyRuns(p)
+	b.learnExitLock(p)
+	fallback := b.keyFallback(p)
+	if o := b.activeOverride(); o != nil {
+		b.tracef("chose override: told to %s until %s", o.Goal, o.Until.Format("15:04:05"))
+		if o.Goal == "fight" {
+			return Action{Goal: "enemy"}
+		}
+		return Action{Goal: "override"}
+	} else if b.unsticking > 0 {
+		b.tracef("chose unstick: %d ticks left of jogging %s to get out of wherever we're wedged", b.unsticking, b.unstickDir)
+		return Action{Goal: "unstick"}
+	} else if step, ok := b.scriptStep(p); ok {
+		b.tracef("chose %s: step %d of the script", step.Goal, b.script.current+1)
+		return Action{Goal: step.Goal, Loc: step.Loc}
+	} else if b.warmingUp() {
+		b.tracef("chose warmup: still mapping, %d ticks in", b.warmupTicks)
+		return Action{Goal: "warmup"}
+	} else if b.cfg.RushExit && b.canExit(p) && p.Exit != nil && !b.enemyWithin(b.panicDistance(p.Health)) {
+		if b.enemyBlocking(*p.Exit) {
+			b.tracef("chose enemy: they're blocking the only way we know to the exit")
+			return Action{Goal: "enemy"}
+		}
+		b.tracef("chose exit: we have the key or don't need it, and nobody is close enough to worry about")
+		return Action{Goal: "exit"}
+	} else if b.strafing.Load() > 0 {
+		b.tracef("chose strafe: we've just taken damage, %d ticks left of sidestepping", b.strafing.Load())
+		return Action{Goal: "strafe"}
+	} else if _, ok := b.dodgeDirection(b.selfLoc()); ok {
+		b.tracef("chose dodge: the enemy is facing %s, straight at us", b.snapshotEnemy().Dir)
+		return Action{Goal: "dodge"}
+	} else if _, ok := b.enemyInSight(b.selfLoc()); ok && p.Health < b.cfg.EvadeHealth {
+		b.tracef("chose evade: health is %d and there's an enemy in sight", p.Health)
+		return Action{Goal: "evade"}
+	} else if b.defendingExit(p) {
+		b.tracef("chose defend: we have the key and there's an enemy between us and the exit, %.0f away", distanceBetween(b.selfLoc(), *p.Exit))
+		return Action{Goal: "defend"}
+	} else if b.repositioning > 0 {
+		b.tracef("chose reposition: %d ticks left of moving %s to a new firing spot", b.repositioning, b.repositionDir)
+		return Action{Goal: "reposition"}
+	} else if b.cfg.Weighted {
+		return Action{Goal: b.weightedTarget(p, fallback)}
+	} else if b.seekingAmmo {
+		b.tracef("chose ammo: ammo is %d, restocking to %d", p.Ammo, b.cfg.ResumeAmmo)
+		return Action{Goal: "ammo"}
+	} else if b.seekingFood {
+		b.tracef("chose food: health is %d, recovering to %d", p.Health, b.cfg.ResumeHealth)
+		return Action{Goal: "food"}
+	} else if goal, loc, why, ok := b.objective(p, fallback); ok {
+		here := b.selfLoc()
+		if enemy, seen := b.enemyInSight(here); seen && b.scoreTarget("enemy", here, enemy) > b.scoreTarget(goal, here, loc) {
+			b.tracef("chose enemy: the scorer rates them above the %s", goal)
+			return Action{Goal: "enemy"}
+		}
+		if b.enemyBlocking(loc) {
+			b.tracef("chose enemy: they're blocking the only way we know to the %s", goal)
+			return Action{Goal: "enemy"}
+		}
+		b.tracef("chose %s: %s", goal, why)
+		return Action{Goal: goal}
+	} else if b.aggressionFactor(p.Health) < 1 && b.enemyWithin(math.MaxInt) && !b.enemyWithin(b.engageDistance(p.Health)) {
+		b.tracef("chose flee: at health %d we only take on enemies within %d", p.Health, b.engageDistance(p.Health))
+		return Action{Goal: "flee"}
+	} else if b.cfg.CombatProfile == "kite" && b.enemyWithin(math.MaxInt) {
+		b.tracef("chose kite: enemy in sight")
+		return Action{Goal: "kite"}
+	} else if it := b.interrupted(); it != nil && !b.enemyWithin(math.MaxInt) {
+		b.tracef("chose %s: the fight's over, back to the one at (%d,%d)", it.Type, it.Loc.X, it.Loc.Y)
+		return Action{Goal: it.Type}
+	}
+	b.tracef("chose enemy: nothing more pressing")
+	return Action{Goal: "enemy"}
+}
+
+// CollectorStrategy never goes looking for a fight, though the write loop still shoots back at anyone in
+// sight.  It runs from any enemy within FleeDistance and otherwise gathers whatever it knows of: the exit
+// once it has the key, then the key, then food and ammo
+type CollectorStrategy struct {
+	FleeDistance float64
+}
+
+func (c CollectorStrategy) Decide(s Snapshot) Action {
+	for _, enemy := range s.Enemies {
+		if s.Taken.Sub(enemy.Seen) < time.Second && distanceBetween(s.Player.Loc, enemy.Loc) <= c.FleeDistance {
+			return Action{Goal: "flee"}
+		}
+	}
+	switch {
+	case s.Player.HasKey && s.Player.Exit != nil:
+		return Action{Goal: "exit"}
+	case !s.Player.HasKey && s.Player.MyKey != nil:
+		return Action{Goal: "key"}
+	case s.Items["food"] > 0 && (s.Items["ammo"] == 0 || s.Player.Health <= s.Player.Ammo):
+		return Action{Goal: "food"}
+	case s.Items["ammo"] > 0:
+		return Action{Goal: "ammo"}
+	}
+	// nothing to collect, so look for something: the key's case wanders when we don't know where it is
+	return Action{Goal: "key"}
+}
+
+// the strategy named by -strategy, which Validate has checked
+func (b *Bot) newStrategy(name string) Strategy {
+	if name == "collector" {
+		return CollectorStrategy{FleeDistance: float64(b.cfg.KiteDistance)}
+	}
+	return DefaultStrategy{bot: b}
+}
+
+// SetStrategy has the bot follow a strategy of your own in place of the one it was configured with.
+// Call it before Run
+func (b *Bot) SetStrategy(s Strategy) {
+	b.strategy = s
+}
@@ -0,0 +1,51 @@
+package gauntletbot
+
+import "testing"
+
+// each tick asks the script for its step once: a step finished by the time it starts mustn't be
+// skipped past, or leave us heading for 0,0
+func TestScriptStepOncePerTick(t *testing.T) {
+	steps, err := ParseScript("goto:100,100;goto:200,100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := newTestBot(func(cfg *Config) { cfg.Script = steps })
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	for i, want := range []Loc{{X: 100, Y: 100}, {X: 200, Y: 100}, {X: 200, Y: 100}} {
+		act := DefaultStrategy{bot: b}.Decide(b.Snapshot())
+		if act.Goal != "goto" || act.Loc != want {
+			t.Errorf("tick %d: %+v, want goto %v", i, act, want)
+		}
+	}
+}
+
+// a strategy that counts how often it's asked
+type countingStrategy struct {
+	asked *int
+}
+
+func (c countingStrategy) Decide(s Snapshot) Action {
+	*c.asked++
+	return Action{Goal: "warmup"}
+}
+
+func TestTickAsksStrategy(t *testing.T) {
+	b, _ := newTestBot(nil)
+	asked := 0
+	b.SetStrategy(countingStrategy{asked: &asked})
+	ts := &tickState{dir: "ne", target: "key", shooter: NewShooter(0)}
+	b.tick(nopSender{}, ts)
+	if asked != 1 || ts.target != "warmup" {
+		t.Errorf("asked %d times and went for %s, want once and warmup", asked, ts.target)
+	}
+	if b.tickSnap == nil {
+		t.Error("took no snapshot for a strategy of our own")
+	}
+
+	// our own judgement is asked the same way, from the same snapshot
+	b.SetStrategy(b.newStrategy("default"))
+	b.tick(nopSender{}, ts)
+	if b.tickSnap == nil || ts.target == "warmup" {
+		t.Errorf("went for %s with snapshot %v, want the default strategy to decide from one", ts.target, b.tickSnap)
+	}
+}
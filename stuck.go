@@ -0,0 +1,58 @@
+package gauntletbot
+
+// how close together all our recent positions must be, in game units, for us to count as stuck
+const stuckRadius = 4
+
+// how long we must have been trying to move without getting anywhere before we count as stuck
+const stuckWindowMs = 2000
+
+// how many ticks to jog sideways for once we're stuck
+const unstickTicks = 3
+
+// are we going nowhere?  True if every position in the history is within stuckRadius of the first,
+// however much we may have jiggled back and forth in between
+func isStuck(history []Loc) bool {
+	if len(history) < 2 {
+		return false
+	}
+	for _, loc := range history[1:] {
+		if distanceBetween(history[0], loc) > stuckRadius {
+			return false
+		}
+	}
+	return true
+}
+
+// a way out of wherever we're wedged: a quarter turn either way from the direction we were going, at random
+func (b *Bot) unstickDirection(dir string) string {
+	if b.rng.Intn(2) == 0 {
+		return rotate(dir, 2)
+	}
+	return rotate(dir, -2)
+}
+
+// remember where we've got to after a tick, if we were trying to get somewhere else, and once we've been
+// stuck for stuckWindowMs start jogging sideways.  Returns the direction to explore in from then on, which
+// is the way we jog if we're stuck, so we don't head straight back into the same corner.  Only touched by the write loop
+func (b *Bot) checkStuck(dir string, now Loc) string {
+	goal := b.moveGoal
+	b.moveGoal = nil
+	if goal == nil || distanceBetween(now, *goal) <= float64(b.tileSize()) {
+		// standing still is what we meant to do
+		b.recentLocs = b.recentLocs[:0]
+		return dir
+	}
+	b.recentLocs = append(b.recentLocs, now)
+	window := max(2, stuckWindowMs/b.cfg.TickMs)
+	if len(b.recentLocs) > window {
+		b.recentLocs = b.recentLocs[len(b.recentLocs)-window:]
+	}
+	if len(b.recentLocs) == window && isStuck(b.recentLocs) {
+		b.unstickDir = b.unstickDirection(dir)
+		b.unsticking = unstickTicks
+		b.recentLocs = b.recentLocs[:0]
+		Infof("Stuck at (%d,%d), jogging %s", now.X, now.Y, b.unstickDir)
+		return b.unstickDir
+	}
+	return dir
+}
@@ -0,0 +1,86 @@
+package gauntletbot
+
+import "testing"
+
+func TestIsStuck(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []Loc
+		want    bool
+	}{
+		{"no history", nil, false},
+		{"one position", []Loc{{X: 10, Y: 10}}, false},
+		{"not moved", []Loc{{X: 10, Y: 10}, {X: 10, Y: 10}, {X: 10, Y: 10}}, true},
+		{"jiggling in place", []Loc{{X: 10, Y: 10}, {X: 13, Y: 10}, {X: 10, Y: 12}, {X: 8, Y: 8}}, true},
+		{"at the edge of the radius", []Loc{{X: 10, Y: 10}, {X: 10 + stuckRadius, Y: 10}}, true},
+		{"just past it", []Loc{{X: 10, Y: 10}, {X: 11 + stuckRadius, Y: 10}}, false},
+		{"wandered off and back", []Loc{{X: 10, Y: 10}, {X: 30, Y: 10}, {X: 10, Y: 10}}, false},
+		{"making progress", []Loc{{X: 10, Y: 10}, {X: 14, Y: 10}, {X: 18, Y: 10}}, false},
+	}
+	for _, tt := range tests {
+		if got := isStuck(tt.history); got != tt.want {
+			t.Errorf("%s: isStuck(%v) = %v, want %v", tt.name, tt.history, got, tt.want)
+		}
+	}
+}
+
+func TestCheckStuckJogsSideways(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.TickMs = 500 })
+	window := stuckWindowMs / b.cfg.TickMs
+	here := Loc{X: 100, Y: 100}
+	for i := 1; i < window; i++ {
+		b.moveGoal = &Loc{X: 200, Y: 100}
+		if dir := b.checkStuck("e", here); dir != "e" {
+			t.Fatalf("gave up on east after %d ticks, want %d", i, window)
+		}
+	}
+	b.moveGoal = &Loc{X: 200, Y: 100}
+	dir := b.checkStuck("e", here)
+	if dir != "n" && dir != "s" {
+		t.Fatalf("stuck going east, jogged %s, want north or south", dir)
+	}
+	if b.unsticking != unstickTicks || b.unstickDir != dir {
+		t.Errorf("set %d ticks jogging %s, want %d jogging %s", b.unsticking, b.unstickDir, unstickTicks, dir)
+	}
+
+	// meaning to stand still isn't being stuck
+	for i := 0; i < 2*window; i++ {
+		b.moveGoal = &here
+		if dir := b.checkStuck("e", here); dir != "e" {
+			t.Fatalf("counted holding still as stuck, jogged %s", dir)
+		}
+	}
+}
+
+func TestJogOutOfCorner(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.TickMs = 500 })
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	here := b.selfLoc()
+	ts := &tickState{dir: "ne", target: "enemy", shooter: NewShooter(0)}
+	// wedged in a corner: every move we make leaves us where we were, bouncing ne and nw
+	var bounced string
+	for i := 0; i < stuckWindowMs/b.cfg.TickMs; i++ {
+		b.tick(nopSender{}, ts)
+		if ts.target != "enemy" {
+			t.Fatalf("tick %d went for %s before we were stuck, want to carry on exploring", i+1, ts.target)
+		}
+		bounced = newDirection(ts.dir, here, here, bounceSensitivity)
+		ts.dir = b.checkStuck(bounced, here)
+	}
+	jog := b.unstickDir
+	if b.unsticking != unstickTicks || (jog != rotate(bounced, 2) && jog != rotate(bounced, -2)) {
+		t.Fatalf("%d ticks jogging %q after bouncing %s in a corner, want %d at right angles", b.unsticking, jog, bounced, unstickTicks)
+	}
+	toward := projectDir(here, jog, 1)
+	want := Loc{X: sign(toward.X - here.X), Y: sign(toward.Y - here.Y)}
+	for i := 0; i < unstickTicks; i++ {
+		b.tick(nopSender{}, ts)
+		if ts.target != "unstick" || b.moveGoal == nil || (Loc{X: sign(b.moveGoal.X - here.X), Y: sign(b.moveGoal.Y - here.Y)}) != want {
+			t.Fatalf("jog tick %d went for %s to %v, want to unstick %s", i+1, ts.target, b.moveGoal, jog)
+		}
+	}
+	b.tick(nopSender{}, ts)
+	if ts.target == "unstick" {
+		t.Errorf("still jogging after %d ticks", unstickTicks)
+	}
+}
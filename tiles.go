@@ -0,0 +1,63 @@
+package gauntletbot
+
+import (
+	"sort"
+)
+
+const minSpacingSamples = 50 // gaps between tiles to see before trusting the commonest one as the tile size
+
+// the size of a map tile, as configured or as learnt from the server
+func (b *Bot) tileSize() int {
+	return int(b.tile.Load())
+}
+
+// how far an exploration step goes, scaled to the tile size
+func (b *Bot) moveStep() int {
+	return b.cfg.MoveStep * b.tileSize() / b.cfg.TileSize
+}
+
+// the gaps between neighbouring tiles in each row and column of a batch of reported positions tell
+// us how big tiles are.  Once one gap clearly dominates we adopt it; if none does, we keep the default
+func (b *Bot) learnTileSize(locs []Loc) {
+	if b.tileSettled || len(locs) < 2 {
+		return
+	}
+	rows := make(map[int][]int)
+	cols := make(map[int][]int)
+	for _, l := range locs {
+		rows[l.Y] = append(rows[l.Y], l.X)
+		cols[l.X] = append(cols[l.X], l.Y)
+	}
+	for _, lines := range []map[int][]int{rows, cols} {
+		for _, line := range lines {
+			sort.Ints(line)
+			for i := 1; i < len(line); i++ {
+				if gap := line[i] - line[i-1]; gap > 0 {
+					b.spacing[gap]++
+				}
+			}
+		}
+	}
+
+	total := 0
+	commonest := 0
+	for gap, count := range b.spacing {
+		total += count
+		if count > b.spacing[commonest] || (count == b.spacing[commonest] && gap < commonest) {
+			commonest = gap
+		}
+	}
+	if total < minSpacingSamples {
+		return
+	}
+	b.tileSettled = true
+	if b.spacing[commonest]*10 < total*6 {
+		Infof("Can't tell the tile size from the map, staying with %d", b.tileSize())
+		return
+	}
+	if commonest != b.tileSize() {
+		Infof("Tiles look to be %d across", commonest)
+		b.tile.Store(int64(commonest))
+		b.forgetAllSightLines()
+	}
+}
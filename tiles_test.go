@@ -0,0 +1,48 @@
+package gauntletbot
+
+import "testing"
+
+func TestLearnTileSize(t *testing.T) {
+	// n tiles along a row from 0,0, each gap apart
+	tileRow := func(n int, gap int) []Loc {
+		locs := make([]Loc, n)
+		for i := range locs {
+			locs[i] = Loc{X: i * gap}
+		}
+		return locs
+	}
+	grid := func(n int, gap int) []Loc {
+		var locs []Loc
+		for x := 0; x < n; x++ {
+			for y := 0; y < n; y++ {
+				locs = append(locs, Loc{X: x * gap, Y: y * gap})
+			}
+		}
+		return locs
+	}
+	tests := []struct {
+		name    string
+		batches [][]Loc
+		want    int
+		settled bool
+	}{
+		{"a long row", [][]Loc{tileRow(60, 16)}, 16, true},
+		{"too few gaps yet", [][]Loc{tileRow(30, 16)}, 8, false},
+		{"enough over two batches", [][]Loc{tileRow(30, 16), tileRow(30, 16)}, 16, true},
+		{"a grid", [][]Loc{grid(6, 12)}, 12, true},
+		{"the size we already had", [][]Loc{tileRow(60, 8)}, 8, true},
+		{"repeats don't count", [][]Loc{append(tileRow(30, 16), tileRow(30, 16)...)}, 8, false},
+		{"dominant through the gaps", [][]Loc{tileRow(41, 16), tileRow(21, 48)}, 16, true},
+		{"no clear winner", [][]Loc{tileRow(31, 10), tileRow(31, 20)}, 8, true},
+		{"settled, so later batches don't count", [][]Loc{tileRow(60, 16), tileRow(200, 32)}, 16, true},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBot(func(cfg *Config) { cfg.TileSize = 8 })
+		for _, batch := range tt.batches {
+			b.learnTileSize(batch)
+		}
+		if b.tileSize() != tt.want || b.tileSettled != tt.settled {
+			t.Errorf("%s: tile size %d, settled %v, want %d and %v", tt.name, b.tileSize(), b.tileSettled, tt.want, tt.settled)
+		}
+	}
+}
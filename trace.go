@@ -0,0 +1,71 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// SetTrace turns the per-tick decision trace on or off while the bot is running
+func (b *Bot) SetTrace(on bool) {
+	b.tracing.Store(on)
+}
+
+// POST /trace?on=1 turns the decision trace on, and on=0 off
+func (b *Bot) handleTrace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "POST with on=1 or on=0", http.StatusMethodNotAllowed)
+		return
+	}
+	on, err := strconv.ParseBool(r.FormValue("on"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad on %q, want 1 or 0", r.FormValue("on")), http.StatusBadRequest)
+		return
+	}
+	b.SetTrace(on)
+	Infof("Trace set to %t from %s", on, r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (b *Bot) tracef(format string, args ...interface{}) {
+	if b.tracing.Load() {
+		log.Printf("TRACE "+format+"\n", args...)
+	}
+}
+
+// log everything we could be going after this tick, and what we know about each
+func (b *Bot) traceCandidates(here Loc) {
+	if !b.tracing.Load() {
+		return
+	}
+	p := b.snapshotPlayer()
+	b.tracef("at (%d,%d) health %d ammo %d key %t", here.X, here.Y, p.Health, p.Ammo, p.HasKey)
+	b.traceLoc("key", here, p.MyKey)
+	b.traceLoc("exit", here, p.Exit)
+	if e := b.snapshotEnemy(); e.Loc == nil {
+		b.tracef("candidate enemy: none seen")
+	} else {
+		b.traceLoc("enemy", here, e.Loc)
+		b.tracef("  last seen %s ago", b.clock.Now().Sub(e.Seen))
+	}
+	for _, itemType := range []string{"ammo", "food"} {
+		items := b.itemsOf(itemType)
+		if len(items) == 0 {
+			b.tracef("candidate %s: none known", itemType)
+		}
+		for _, item := range items {
+			b.tracef("candidate %s: (%d,%d) distance %.0f visible %t", itemType, item.Loc.X, item.Loc.Y,
+				distanceBetween(here, item.Loc), b.canSeeItem(here, item.Loc))
+		}
+	}
+}
+
+func (b *Bot) traceLoc(name string, here Loc, loc *Loc) {
+	if loc == nil {
+		b.tracef("candidate %s: location unknown", name)
+		return
+	}
+	b.tracef("candidate %s: (%d,%d) distance %.0f visible %t", name, loc.X, loc.Y, distanceBetween(here, *loc), b.canSeeItem(here, *loc))
+}
@@ -0,0 +1,163 @@
+package gauntletbot
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Transport carries datagrams to and from the server.  A connected *net.UDPConn is one
+type Transport interface {
+	Read(p []byte) (int, error)
+	Write(p []byte) (int, error)
+	Close() error
+}
+
+// Sender is where the commands we send to the server go
+type Sender interface {
+	Send(datagram []byte) error
+}
+
+// connSender sends over whatever connection the bot currently has, which is a *net.UDPConn
+// (or a capture wrapping one) that the watchdog may replace
+type connSender struct {
+	b *Bot
+}
+
+func (s connSender) Send(datagram []byte) error {
+	_, err := s.b.transport().Write(datagram)
+	return err
+}
+
+// logSender logs commands instead of sending them, for -dryrun
+type logSender struct{}
+
+func (logSender) Send(datagram []byte) error {
+	Infof("DRYRUN %s", datagram)
+	return nil
+}
+
+// capture taps a Transport, logging every datagram that passes through it in either direction,
+// one per line: the time, "in" or "out", the length and the quoted payload
+type capture struct {
+	Transport
+	mu      sync.Mutex
+	clock   Clock
+	out     io.Writer
+	inbound bool // only log what we receive, for -record
+}
+
+func newCapture(t Transport, out io.Writer, clock Clock) *capture {
+	return &capture{Transport: t, out: out, clock: clock}
+}
+
+// a capture of just the datagrams the server sends us, which is all a replay needs
+func newRecorder(t Transport, out io.Writer, clock Clock) *capture {
+	return &capture{Transport: t, out: out, clock: clock, inbound: true}
+}
+
+func (c *capture) Read(p []byte) (int, error) {
+	n, err := c.Transport.Read(p)
+	if n > 0 {
+		c.record("in", p[:n])
+	}
+	return n, err
+}
+
+func (c *capture) Write(p []byte) (int, error) {
+	n, err := c.Transport.Write(p)
+	if err == nil && !c.inbound {
+		c.record("out", p)
+	}
+	return n, err
+}
+
+func (c *capture) record(direction string, datagram []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(c.out, "%s %s %d %s\n", c.clock.Now().Format(captureTimeFormat), direction, len(datagram), strconv.Quote(string(datagram)))
+}
+
+const captureTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// budgeted is a Sender that can only take so many packets at the moment without dropping some,
+// so a caller with several to send can choose which go
+type budgeted interface {
+	Budget() int
+	Shed(n int)
+}
+
+// rateLimiter caps how many commands a Sender gets per second with a token bucket, dropping any over the
+// limit rather than letting them build up and arrive late.  Drops are logged at most once a second
+type rateLimiter struct {
+	Sender
+	mu       sync.Mutex
+	clock    Clock
+	rate     float64 // tokens added per second
+	burst    float64 // most tokens the bucket holds
+	tokens   float64
+	last     time.Time
+	dropped  int
+	lastWarn time.Time
+}
+
+// a limiter allowing pps packets a second, in bursts of up to a tenth of that
+func newRateLimiter(s Sender, pps int, clock Clock) *rateLimiter {
+	burst := math.Max(1, float64(pps)/10)
+	return &rateLimiter{Sender: s, clock: clock, rate: float64(pps), burst: burst, tokens: burst, last: clock.Now()}
+}
+
+func (r *rateLimiter) Send(datagram []byte) error {
+	if !r.allow() {
+		return nil
+	}
+	return r.Sender.Send(datagram)
+}
+
+// Budget is how many packets could go right now without any being dropped
+func (r *rateLimiter) Budget() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refill(r.clock.Now())
+	return int(r.tokens)
+}
+
+// Shed counts packets the sender held back to stay within the Budget as dropped
+func (r *rateLimiter) Shed(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.drop(r.clock.Now(), n)
+}
+
+// take a token if there's one to take
+func (r *rateLimiter) allow() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.clock.Now()
+	r.refill(now)
+	if r.tokens >= 1 {
+		r.tokens--
+		return true
+	}
+	r.drop(now, 1)
+	return false
+}
+
+// top the bucket up for the time since we last did.  Must be called with mu held
+func (r *rateLimiter) refill(now time.Time) {
+	r.tokens = math.Min(r.burst, r.tokens+now.Sub(r.last).Seconds()*r.rate)
+	r.last = now
+}
+
+// count dropped packets, warning about them at most once a second.  Must be called with mu held
+func (r *rateLimiter) drop(now time.Time, n int) {
+	r.dropped += n
+	if now.Sub(r.lastWarn) >= time.Second {
+		Warnf("Dropped %d packets to stay under %g a second", r.dropped, r.rate)
+		r.dropped = 0
+		r.lastWarn = now
+	}
+}
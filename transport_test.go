@@ -0,0 +1,116 @@
+package gauntletbot
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a Sender that counts what it's given
+type countingSender struct {
+	sent int
+}
+
+func (s *countingSender) Send([]byte) error {
+	s.sent++
+	return nil
+}
+
+func TestRateLimiter(t *testing.T) {
+	var logged bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&logged)
+	defer log.SetOutput(prev)
+
+	clock := NewFakeClock(time.Date(2022, 2, 19, 12, 0, 0, 0, time.UTC))
+	counter := &countingSender{}
+	limiter := newRateLimiter(counter, 100, clock)
+	// 1000 sends a millisecond apart, ten times what's allowed
+	for i := 0; i < 1000; i++ {
+		if err := limiter.Send([]byte("fire:")); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Millisecond)
+	}
+	// a second's worth, plus the burst we start with
+	if counter.sent > 110 || counter.sent < 100 {
+		t.Errorf("sent %d of 1000 packets in a second, want 100 to 110", counter.sent)
+	}
+	// and drops are only worth a warning once a second
+	if warnings := strings.Count(logged.String(), "WARN Dropped"); warnings != 1 {
+		t.Errorf("warned %d times about dropped packets in a second, want once:\n%s", warnings, logged.String())
+	}
+}
+
+// a Transport that keeps everything written to it and never has anything to read
+type recordingTransport struct {
+	mu      sync.Mutex
+	written []string
+}
+
+func (r *recordingTransport) Read([]byte) (int, error) { return 0, io.EOF }
+func (r *recordingTransport) Close() error             { return nil }
+
+func (r *recordingTransport) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.written = append(r.written, string(p))
+	return len(p), nil
+}
+
+func (r *recordingTransport) sent() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.written...)
+}
+
+func TestRateLimitedJoin(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.MaxPPS = 1 })
+	conn := &recordingTransport{}
+	b.conn = conn
+	// spend the only packet the limiter has, then join twice, as a redial or a new round would
+	b.sender.Send([]byte("fire:"))
+	b.join("warrior")
+	b.join("warrior")
+	joins := 0
+	for _, msg := range conn.sent() {
+		if strings.HasPrefix(msg, "requestjoin:") {
+			joins++
+		}
+	}
+	if joins != 2 {
+		t.Errorf("%d of 2 joins reached the server under the rate limit: %q", joins, conn.sent())
+	}
+}
+
+func TestRateLimitedPlan(t *testing.T) {
+	// each tick faces, fires and moves.  The burst is a tenth of a second's packets
+	tests := []struct {
+		pps  int
+		want []string
+	}{
+		{pps: 10, want: []string{"moveto:140,100"}},
+		{pps: 20, want: []string{"facedirection:e", "moveto:140,100"}},
+		{pps: 30, want: []string{"facedirection:e", "fire:", "moveto:140,100"}},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBot(func(cfg *Config) {
+			cfg.MaxPPS = tt.pps
+			cfg.ShotDelay = 0
+		})
+		conn := &recordingTransport{}
+		b.conn = conn
+		b.handleMessage("playerupdate:100,100,10,10,False")
+		b.handleMessage("nearbyplayer:orc,grunt,140,100")
+		ts := &tickState{dir: "ne", target: "key", shooter: NewShooter(0)}
+		sent := b.tick(b.sender, ts)
+		if !reflect.DeepEqual(sent, tt.want) || !reflect.DeepEqual(conn.sent(), tt.want) {
+			t.Errorf("%d a second: planned %q and sent %q, want %q", tt.pps, sent, conn.sent(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package gauntletbot
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The main game logic, responsible for writing move messages to the server.
+// Runs until the context is cancelled or the deadline (if set) passes
+func (b *Bot) writeLoop(ctx context.Context, deadline time.Time) {
+	ts := &tickState{dir: b.cfg.StartDir, target: "key", shooter: NewShooter(b.cfg.ShotDelay)}
+	autoDir := ts.dir == "auto"
+	if autoDir {
+		ts.dir = "ne"
+	}
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if !deadline.IsZero() && b.clock.Now().After(deadline) {
+			Infof("Reached maximum runtime")
+			return
+		}
+		if autoDir {
+			if spawnDir, ok := b.spawnDirection(b.snapshotPlayer().Loc); ok {
+				Infof("Setting off %s", spawnDir)
+				ts.dir = spawnDir
+				autoDir = false
+			}
+		}
+		b.think(func() {
+			lastLoc := b.snapshotPlayer().Loc
+			actions := b.tick(b.sender, ts)
+			b.tracef("tick sent %q", actions)
+			time.Sleep(b.tickDuration()) // don't DDoS the server, tickDuration never goes below minTickMs
+			now := b.snapshotPlayer().Loc
+			bounced := newDirection(ts.dir, lastLoc, now, bounceSensitivity)
+			ts.dir = b.biasDirection(ts.dir, bounced, now)
+			ts.dir = b.checkStuck(ts.dir, now)
+			b.expireItems()
+			b.expireEnemy()
+			b.expireWalls()
+			b.pruneWalls(now)
+			if p := b.snapshotPlayer(); p.Exit != nil {
+				b.metrics.setExitDistance(distanceBetween(p.Loc, *p.Exit), true)
+			}
+		})
+	}
+}
+
+// what the write loop carries from one tick to the next
+type tickState struct {
+	dir     string // which way we're exploring
+	target  string // what we went after last tick
+	shooter *Shooter
+}
+
+// one tick's worth of decisions: first where to move, then whether to shoot.  The commands they make are
+// held back and then sent through out in a fixed order, facedirection, fire and then moveto, so we shoot
+// from where we are rather than where we're going.  If out is rate limited only as many as it can take
+// go, the moveto first among them.  Returns the commands sent, in the order they went
+func (b *Bot) tick(out Sender, ts *tickState) []string {
+	b.planning = true
+	b.plan = nil
+	b.pathCosts = make(map[sightLine]float64)
+	b.tickSnap = nil
+	here := b.selfLoc()
+	b.traceCandidates(here)
+	s := b.tickSnapshot()
+	p := s.Player
+	act := b.strategy.Decide(s)
+	ts.target = act.Goal
+	debugf("Target: %s", ts.target)
+	b.noteInterruption(ts.target)
+	enemy := b.snapshotEnemy().Loc
+	switch ts.target {
+	case "override":
+		if o := b.activeOverride(); o != nil && !b.approach(here, o.Loc) {
+			b.moveTo(o.Loc)
+		}
+	case "goto":
+		if !b.approach(here, act.Loc) {
+			b.moveTo(act.Loc)
+		}
+	case "warmup":
+		b.wander(here, ts.dir)
+	case "strafe":
+		if strafeDir, ok := b.strafeDirection(here); ok {
+			b.moveToDir(strafeDir)
+		} else {
+			b.wander(here, ts.dir)
+		}
+		if b.strafing.Add(-1) <= 0 {
+			b.strafeSide = 0
+		}
+	case "dodge":
+		if dodgeDir, ok := b.dodgeDirection(here); ok {
+			b.moveToDir(dodgeDir)
+		}
+	case "flee":
+		if enemy != nil {
+			b.moveToDir(fleeDirection(here, *enemy))
+		}
+	case "evade":
+		// food is what we need, so grab any we can see; otherwise just get away
+		if food, ok := b.itemTarget(here, "food"); ok {
+			b.moveTo(food.Loc)
+		} else if foe, ok := b.enemyInSight(here); ok {
+			b.moveToDir(fleeDirection(here, foe))
+		}
+	case "unstick":
+		b.moveToDir(b.unstickDir)
+		b.unsticking--
+	case "reposition":
+		b.moveToDir(b.repositionDir)
+		b.repositioning--
+	case "defend":
+		// hold still so shoot can clear the way, then the exit case takes us through
+		b.holdStill(here)
+	case "kite":
+		// back off until they're at arm's length, then hold there and let shoot do the rest
+		if enemy != nil && distanceBetween(here, *enemy) < float64(b.cfg.KiteDistance) {
+			b.moveToDir(fleeDirection(here, *enemy))
+		}
+	case "key":
+		if p.MyKey == nil || !b.approach(here, *p.MyKey) {
+			b.wander(here, ts.dir)
+		}
+	case "exit":
+		if p.Exit == nil || !b.approach(here, *p.Exit) {
+			b.wander(here, ts.dir)
+		}
+	case "ammo":
+		if ammo, ok := b.itemTarget(here, "ammo"); ok {
+			b.moveTo(ammo.Loc)
+		} else if spawn, ok := b.spawnTarget(here, "ammo"); ok {
+			if !b.approach(here, spawn) {
+				b.moveTo(spawn)
+			}
+		} else {
+			b.wander(here, ts.dir)
+		}
+	case "food":
+		if food, ok := b.itemTarget(here, "food"); ok {
+			debugf("Heading for food at (%d,%d)", food.Loc.X, food.Loc.Y)
+			b.moveTo(food.Loc)
+		} else if spawn, ok := b.spawnTarget(here, "food"); ok {
+			if !b.approach(here, spawn) {
+				b.moveTo(spawn)
+			}
+		} else {
+			b.wander(here, ts.dir)
+		}
+	case "enemy":
+		if enemy != nil && b.canSeeItem(here, *enemy) &&
+			(b.cfg.EngageDistance == 0 || b.enemyWithin(b.engageDistance(p.Health))) {
+			debugf("Heading for enemy at (%d,%d)", enemy.X, enemy.Y)
+			b.moveTo(*enemy)
+		} else {
+			b.wander(here, ts.dir)
+		}
+	}
+	// only count down to a shot while there's someone to shoot at, so we don't spend ammo
+	// the moment a distant enemy wanders into view after a long quiet spell
+	if _, ok := b.enemyInSight(here); !ok {
+		ts.shooter.Reset()
+	} else if ts.shooter.ShouldFire() {
+		b.shoot()
+	}
+	b.planning = false
+	actions := b.plan
+	b.plan = nil
+	actions = fitBudget(out, actions)
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actionOrder(actions[i]) < actionOrder(actions[j])
+	})
+	for _, action := range actions {
+		b.transmit(out, action)
+	}
+	return actions
+}
+
+// where a command goes in a tick's running order
+func actionOrder(command string) int {
+	switch {
+	case strings.HasPrefix(command, "facedirection:"):
+		return 0
+	case strings.HasPrefix(command, "fire:"):
+		return 1
+	case strings.HasPrefix(command, "moveto:"), strings.HasPrefix(command, "movedirection:"):
+		return 2
+	}
+	return 3
+}
+
+// if out is rate limited and can't take all of a tick's commands, keep the ones that matter most: the move,
+// so the limiter never leaves us shooting on the spot, then the facing, and last the shot, which is no use
+// without the facing.  The rest are counted as dropped
+func fitBudget(out Sender, actions []string) []string {
+	limited, ok := out.(budgeted)
+	if !ok {
+		return actions
+	}
+	budget := max(limited.Budget(), 0)
+	if len(actions) <= budget {
+		return actions
+	}
+	kept := append([]string(nil), actions...)
+	sort.SliceStable(kept, func(i, j int) bool {
+		return budgetPriority(kept[i]) < budgetPriority(kept[j])
+	})
+	debugf("Over the packet budget, holding back %q", kept[budget:])
+	limited.Shed(len(kept) - budget)
+	return kept[:budget]
+}
+
+// which of a tick's commands to keep first when they won't all fit through the rate limiter
+func budgetPriority(command string) int {
+	switch {
+	case strings.HasPrefix(command, "moveto:"), strings.HasPrefix(command, "movedirection:"):
+		return 0
+	case strings.HasPrefix(command, "facedirection:"):
+		return 1
+	case strings.HasPrefix(command, "fire:"):
+		return 2
+	}
+	return 3
+}
+
+// the objective the bottom of the ladder goes for, if there's one we can act on: the exit once we have the key,
+// the key if we know where it is and haven't given up on it, or the exit if we have.  And why, for the trace
+func (b *Bot) objective(p Player, fallback string) (string, Loc, string, bool) {
+	switch {
+	case p.HasKey && p.Exit != nil:
+		return "exit", *p.Exit, "we have the key", true
+	case !p.HasKey && p.MyKey != nil && fallback == "":
+		return "key", *p.MyKey, "we know where it is", true
+	case fallback == "exit" && p.Exit != nil && !b.exitLocked:
+		return "exit", *p.Exit, "we've given up on finding the key", true
+	}
+	return "", Loc{}, "", false
+}
+
+// start a supply run when health or ammo drops below its flee threshold and only end it once it's back
+// up to the resume threshold, so hovering around one number doesn't flip us between fighting and fleeing
+func (b *Bot) updateSupplyRuns(p Player) {
+	if p.Health < b.cfg.FleeHealth {
+		b.seekingFood = true
+	} else if p.Health >= b.cfg.ResumeHealth {
+		b.seekingFood = false
+	}
+	if p.Ammo < b.cfg.FleeAmmo {
+		b.seekingAmmo = true
+	} else if p.Ammo >= b.cfg.ResumeAmmo {
+		b.seekingAmmo = false
+	}
+}
+
+// are we still just exploring, before going after anything?  Warmup lasts until we've spent WarmupTicks
+// on it or know WarmupCoverage of the area around us, whichever comes first
+func (b *Bot) warmingUp() bool {
+	if b.warmedUp {
+		return false
+	}
+	b.warmupTicks++
+	if (b.cfg.WarmupTicks > 0 && b.warmupTicks > b.cfg.WarmupTicks) ||
+		(b.cfg.WarmupCoverage > 0 && b.coverageWithin(b.snapshotPlayer().Loc, exploreRadius) >= b.cfg.WarmupCoverage) {
+		Infof("Finished warming up after %d ticks", b.warmupTicks-1)
+		b.warmedUp = true
+		return false
+	}
+	return true
+}
+
+// once we've spent KeyTimeout without getting our key, KeyGiveUp says what to do instead: exit or frag.
+// Until then, or once we have it, or if we're told to keep trying, there's nothing to fall back to
+func (b *Bot) keyFallback(p Player) string {
+	b.goalMutex.Lock()
+	defer b.goalMutex.Unlock()
+	if p.HasKey || b.cfg.KeyTimeout == 0 || b.cfg.KeyGiveUp == "keep" {
+		b.keySince = time.Time{}
+		b.keyGivenUp = false
+		return ""
+	}
+	if b.keySince.IsZero() {
+		b.keySince = b.clock.Now()
+	}
+	if b.clock.Now().Sub(b.keySince) < b.cfg.KeyTimeout {
+		return ""
+	}
+	if !b.keyGivenUp {
+		Infof("No key after %s, switching to %s", b.cfg.KeyTimeout, b.cfg.KeyGiveUp)
+		b.keyGivenUp = true
+	}
+	return b.cfg.KeyGiveUp
+}
+
+// if a fight or a dodge takes us away from an item we were heading for, remember it to come back to
+func (b *Bot) noteInterruption(target string) {
+	fighting := false
+	switch target {
+	case "ammo", "food":
+		return
+	case "enemy", "kite", "flee", "dodge", "reposition":
+		// with nobody about, "enemy" is just exploring: whatever we were after is no longer worth it
+		fighting = b.enemyWithin(math.MaxInt)
+	}
+	b.goalMutex.Lock()
+	defer b.goalMutex.Unlock()
+	if fighting && b.headingFor != nil {
+		Infof("Interrupted on the way to %s at (%d,%d)", b.headingFor.Type, b.headingFor.Loc.X, b.headingFor.Loc.Y)
+		b.state.Interrupted = b.headingFor
+	}
+	b.headingFor = nil
+}
+
+// the item a fight took us away from, if there is one
+func (b *Bot) interrupted() *Item {
+	b.goalMutex.Lock()
+	defer b.goalMutex.Unlock()
+	return b.state.Interrupted
+}
+
+// the item of a type to go for: the one we were interrupted on the way to if it's still there, else the best we can see
+func (b *Bot) itemTarget(here Loc, itemType string) (*Item, bool) {
+	// checking on it takes the map and item locks, so it's done outside goalMutex
+	it := b.interrupted()
+	resume := it != nil && it.Type == itemType && distanceBetween(here, it.Loc) > float64(b.tileSize()) && b.stillThere(*it) && b.canSeeItem(here, it.Loc)
+	item, ok := it, true
+	if !resume {
+		item, ok = b.bestVisibleItem(here, b.itemsOf(itemType))
+	}
+	b.goalMutex.Lock()
+	defer b.goalMutex.Unlock()
+	// one we've got to, or lost, isn't worth coming back for.  Unless a new round has cleared it meanwhile
+	if it != nil && it.Type == itemType && !resume && b.state.Interrupted == it {
+		b.state.Interrupted = nil
+	}
+	b.headingFor = item
+	return item, ok
+}
+
+// is something of this type still known to be where we last headed for it (or, for a cluster, near there)?
+func (b *Bot) stillThere(target Item) bool {
+	reach := math.Max(float64(b.tileSize()), float64(b.cfg.ClusterRadius))
+	for _, item := range b.itemsOf(target.Type) {
+		if distanceBetween(item.Loc, target.Loc) <= reach {
+			return true
+		}
+	}
+	return false
+}
+
+// the scripted step to work on, if we have a script that isn't finished
+func (b *Bot) scriptStep(p Player) (ScriptStep, bool) {
+	if b.script == nil {
+		return ScriptStep{}, false
+	}
+	return b.script.step(b, p)
+}
+
+// would reaching the exit get us out?
+func (b *Bot) canExit(p Player) bool {
+	return p.HasKey || !b.exitLocked
+}
+
+// if we've stood on the exit without the key for a while and the round carries on, it must need the key
+func (b *Bot) learnExitLock(p Player) {
+	if b.exitLocked || p.HasKey || p.Exit == nil || distanceBetween(p.Loc, *p.Exit) > float64(b.tileSize()) {
+		b.atExitSince = time.Time{}
+		return
+	}
+	if b.atExitSince.IsZero() {
+		b.atExitSince = b.clock.Now()
+	} else if b.clock.Now().Sub(b.atExitSince) > exitLockWait {
+		Infof("The exit didn't let us out without the key, we'll need it")
+		b.exitLocked = true
+	}
+}
+
+// is an enemy we can see standing in the way of the only route we know to goal?
+func (b *Bot) enemyBlocking(goal Loc) bool {
+	e := b.snapshotEnemy()
+	return b.enemyWithin(math.MaxInt) && e.Loc != nil && b.enemyBlocksPath(b.selfLoc(), goal, *e.Loc)
+}
+
+// have we recently seen an enemy within the given distance of us?
+func (b *Bot) enemyWithin(distance int) bool {
+	e := b.snapshotEnemy()
+	if e.Loc == nil || !e.Seen.After(b.clock.Now().Add(-1*time.Second)) {
+		return false
+	}
+	return distanceBetween(b.snapshotPlayer().Loc, *e.Loc) <= float64(distance)
+}
+
+func distanceBetween(a Loc, b Loc) float64 {
+	return math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+}
+
+// how far apart two points are by a DistanceMetric: euclidean as the crow flies, manhattan for a
+// server that only moves us along one axis at a time, chebyshev for one that moves diagonals as fast as straights
+func metricDistance(metric string, a Loc, b Loc) float64 {
+	dx := math.Abs(float64(a.X - b.X))
+	dy := math.Abs(float64(a.Y - b.Y))
+	switch metric {
+	case "manhattan":
+		return dx + dy
+	case "chebyshev":
+		return math.Max(dx, dy)
+	}
+	return math.Hypot(dx, dy)
+}
+
+// how far apart two points are for choosing between targets, by our DistanceMetric
+func (b *Bot) distance(from Loc, to Loc) float64 {
+	return metricDistance(b.cfg.DistanceMetric, from, to)
+}
+
+// how long to wait before the next tick.  Never less than minTickMs, however the jitter falls
+func (b *Bot) tickDuration() time.Duration {
+	tick := time.Duration(b.cfg.TickMs) * time.Millisecond
+	if b.cfg.JitterMs > 0 {
+		tick += time.Duration(b.rng.Intn(2*b.cfg.JitterMs+1)-b.cfg.JitterMs) * time.Millisecond
+	}
+	return max(tick, minTickMs*time.Millisecond)
+}
@@ -0,0 +1,430 @@
+package gauntletbot
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricDistanceOrdering(t *testing.T) {
+	from := Loc{}
+	points := []Loc{{X: 10, Y: 0}, {X: 6, Y: 6}, {X: 0, Y: 9}, {X: 8, Y: 1}}
+	tests := map[string][]Loc{
+		"euclidean": {{X: 8, Y: 1}, {X: 6, Y: 6}, {X: 0, Y: 9}, {X: 10, Y: 0}},
+		"manhattan": {{X: 0, Y: 9}, {X: 8, Y: 1}, {X: 10, Y: 0}, {X: 6, Y: 6}},
+		"chebyshev": {{X: 6, Y: 6}, {X: 8, Y: 1}, {X: 0, Y: 9}, {X: 10, Y: 0}},
+	}
+	for metric, want := range tests {
+		got := append([]Loc(nil), points...)
+		sort.SliceStable(got, func(i, j int) bool {
+			return metricDistance(metric, from, got[i]) < metricDistance(metric, from, got[j])
+		})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s orders %v, want %v", metric, got, want)
+		}
+	}
+}
+
+func TestDefendHoldsStill(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) { cfg.StrafeTicks = 3 })
+	b.handleMessage("playerupdate:100,100,10,10,True")
+	b.handleMessage("exit:180,100")
+	b.handleMessage("nearbyplayer:orc,grunt,140,100")
+	ts := &tickState{dir: "ne", target: "key", shooter: NewShooter(0)}
+	sent := b.tick(nopSender{}, ts)
+	if ts.target != "defend" {
+		t.Fatalf("went for %s, want defend", ts.target)
+	}
+	if !slices.Contains(sent, "moveto:100,100") {
+		t.Errorf("sent %q, want a moveto where we are", sent)
+	}
+
+	// taking damage means strafing gets priority over standing our ground
+	b.handleMessage("playerupdate:100,100,8,10,True")
+	b.handleMessage("nearbyplayer:orc,grunt,140,100")
+	b.tick(nopSender{}, ts)
+	if ts.target != "strafe" {
+		t.Errorf("went for %s after taking damage, want strafe", ts.target)
+	}
+}
+
+func TestTickJitterFloor(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) {
+		cfg.TickMs = minTickMs
+		cfg.JitterMs = minTickMs - 1
+	})
+	for i := 0; i < 1000; i++ {
+		if tick := b.tickDuration(); tick < minTickMs*time.Millisecond {
+			t.Fatalf("drew a %s tick, under the %dms floor", tick, minTickMs)
+		}
+	}
+	for _, jitter := range []int{-1, minTickMs, 3 * minTickMs} {
+		cfg := DefaultConfig()
+		cfg.TickMs = minTickMs
+		cfg.JitterMs = jitter
+		if cfg.Validate() == nil {
+			t.Errorf("accepted %dms of jitter on a %dms tick", jitter, cfg.TickMs)
+		}
+	}
+}
+
+func TestKeyTimeout(t *testing.T) {
+	b, clock := newTestBot(func(cfg *Config) {
+		cfg.KeyTimeout = 30 * time.Second
+		cfg.KeyGiveUp = "frag"
+	})
+	for round := 1; round <= 2; round++ {
+		b.handleMessage("playerupdate:100,100,10,10,False")
+		if got := b.keyFallback(b.snapshotPlayer()); got != "" {
+			t.Errorf("round %d: fell back to %q as soon as we started looking", round, got)
+		}
+		clock.Advance(29 * time.Second)
+		if got := b.keyFallback(b.snapshotPlayer()); got != "" {
+			t.Errorf("round %d: fell back to %q before the timeout", round, got)
+		}
+		clock.Advance(2 * time.Second)
+		if got := b.keyFallback(b.snapshotPlayer()); got != "frag" {
+			t.Errorf("round %d: fell back to %q after the timeout, want frag", round, got)
+		}
+		b.handleMessage("roundstart")
+	}
+}
+
+func TestSupplyRunHysteresis(t *testing.T) {
+	b, _ := newTestBot(func(cfg *Config) {
+		cfg.FleeHealth, cfg.ResumeHealth = 3, 6
+		cfg.FleeAmmo, cfg.ResumeAmmo = 2, 5
+	})
+	steps := []struct {
+		health, ammo       int
+		seekFood, seekAmmo bool
+	}{
+		{health: 5, ammo: 4},
+		// dropping below the flee thresholds starts a run
+		{health: 2, ammo: 1, seekFood: true, seekAmmo: true},
+		// climbing back between the thresholds, or hovering there, doesn't end it
+		{health: 3, ammo: 2, seekFood: true, seekAmmo: true},
+		{health: 5, ammo: 4, seekFood: true, seekAmmo: true},
+		{health: 4, ammo: 3, seekFood: true, seekAmmo: true},
+		// reaching the resume thresholds does
+		{health: 6, ammo: 4, seekAmmo: true},
+		{health: 6, ammo: 5},
+		// and falling back between them doesn't start another
+		{health: 5, ammo: 3},
+		{health: 3, ammo: 2},
+		{health: 2, ammo: 2, seekFood: true},
+	}
+	for i, step := range steps {
+		b.updateSupplyRuns(Player{Health: step.health, Ammo: step.ammo})
+		if b.seekingFood != step.seekFood || b.seekingAmmo != step.seekAmmo {
+			t.Errorf("step %d, health %d ammo %d: seeking food %v and ammo %v, want %v and %v",
+				i, step.health, step.ammo, b.seekingFood, b.seekingAmmo, step.seekFood, step.seekAmmo)
+		}
+	}
+}
+
+func TestLearnExitLock(t *testing.T) {
+	b, clock := newTestBot(func(cfg *Config) { cfg.ExitNeedsKey = false })
+	if b.exitLocked {
+		t.Fatal("assumed the exit was locked without being told to")
+	}
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	b.handleMessage("exit:102,100")
+	for waited := time.Duration(0); waited <= exitLockWait; waited += 500 * time.Millisecond {
+		b.learnExitLock(b.snapshotPlayer())
+		if b.exitLocked {
+			t.Fatalf("decided the exit was locked after %s on it", waited)
+		}
+		clock.Advance(500 * time.Millisecond)
+	}
+	b.learnExitLock(b.snapshotPlayer())
+	if !b.exitLocked {
+		t.Errorf("still think the exit is open after %s on it without being let out", exitLockWait+500*time.Millisecond)
+	}
+
+	locked, _ := newTestBot(func(cfg *Config) { cfg.ExitNeedsKey = true })
+	if !locked.exitLocked || locked.canExit(Player{}) {
+		t.Error("ExitNeedsKey didn't start us off thinking the exit is locked")
+	}
+}
+
+func TestKiteKeepsDistance(t *testing.T) {
+	tests := []struct {
+		name    string
+		enemy   string
+		retreat bool
+	}{
+		{"too close", "nearbyplayer:orc,grunt,140,100", true},
+		{"at arm's length", "nearbyplayer:orc,grunt,200,100", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := newTestBot(func(cfg *Config) {
+				cfg.CombatProfile = "kite"
+				cfg.KiteDistance = 80
+			})
+			b.handleMessage("playerupdate:100,100,10,10,False")
+			b.handleMessage(tt.enemy)
+			ts := &tickState{dir: "ne", target: "key", shooter: NewShooter(0)}
+			sent := b.tick(nopSender{}, ts)
+			if ts.target != "kite" {
+				t.Fatalf("went for %s, want kite", ts.target)
+			}
+			moved := slices.ContainsFunc(sent, func(cmd string) bool { return strings.HasPrefix(cmd, "moveto:") })
+			if moved != tt.retreat {
+				t.Fatalf("sent %q, want a move %v", sent, tt.retreat)
+			}
+			if tt.retreat && (b.moveGoal.X >= 100 || b.moveGoal.Y != 100) {
+				t.Errorf("backed off to %v, want due west of (100,100), away from the enemy", *b.moveGoal)
+			}
+		})
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	t.Run("ticks", func(t *testing.T) {
+		b, _ := newTestBot(func(cfg *Config) { cfg.WarmupTicks = 3 })
+		b.handleMessage("playerupdate:100,100,10,10,False")
+		b.handleMessage("nearbyitem:" + b.myKeyName() + ",140,100")
+		for i := 1; i <= 3; i++ {
+			if got := b.strategy.Decide(b.Snapshot()).Goal; got != "warmup" {
+				t.Fatalf("tick %d went for %s, want warmup", i, got)
+			}
+		}
+		for i := 4; i <= 5; i++ {
+			if got := b.strategy.Decide(b.Snapshot()).Goal; got != "key" {
+				t.Fatalf("tick %d went for %s after %d ticks of warmup, want key", i, got, b.cfg.WarmupTicks)
+			}
+		}
+	})
+	t.Run("coverage", func(t *testing.T) {
+		b, _ := newTestBot(func(cfg *Config) { cfg.WarmupCoverage = 0.5 })
+		here := Loc{X: tileCentre(b, exploreRadius), Y: tileCentre(b, exploreRadius)}
+		b.handleMessage(fmt.Sprintf("playerupdate:%d,%d,10,10,False", here.X, here.Y))
+		b.handleMessage(fmt.Sprintf("nearbyitem:%s,%d,%d", b.myKeyName(), here.X+40, here.Y))
+		for i := 1; i <= 10; i++ {
+			if got := b.strategy.Decide(b.Snapshot()).Goal; got != "warmup" {
+				t.Fatalf("tick %d went for %s knowing nothing of the map, want warmup", i, got)
+			}
+		}
+		// learn every tile around us
+		for x := 0; x <= 2*exploreRadius; x++ {
+			for y := 0; y <= 2*exploreRadius; y++ {
+				b.setFloor(tileCentre(b, x), tileCentre(b, y))
+			}
+		}
+		if got := b.strategy.Decide(b.Snapshot()).Goal; got != "key" {
+			t.Errorf("went for %s once the map around us was known, want key", got)
+		}
+	})
+}
+
+func TestInterruptedResumes(t *testing.T) {
+	b, clock := newTestBot(nil)
+	b.handleMessage("playerupdate:100,100,10,10,False")
+	b.handleMessage("nearbyitem:ammo,200,100")
+	here := b.selfLoc()
+	if _, ok := b.itemTarget(here, "ammo"); !ok {
+		t.Fatal("didn't go for the ammo in sight")
+	}
+	// with nobody about, "enemy" is just exploring, and the ammo's forgotten
+	b.noteInterruption("enemy")
+	if b.state.Interrupted != nil || b.headingFor != nil {
+		t.Fatalf("remembered %v as interrupted with no enemy about", b.state.Interrupted)
+	}
+
+	b.itemTarget(here, "ammo")
+	b.handleMessage("nearbyplayer:orc,grunt,100,160")
+	b.noteInterruption("enemy")
+	if it := b.state.Interrupted; it == nil || it.Loc != (Loc{X: 200, Y: 100}) {
+		t.Fatalf("interrupted on the way to %v, want the ammo at (200,100)", it)
+	}
+
+	// the enemy's gone a second later, so back to the ammo we were after
+	clock.Advance(2 * time.Second)
+	if got := b.strategy.Decide(b.Snapshot()).Goal; got != "ammo" {
+		t.Fatalf("went for %s once the fight was over, want ammo", got)
+	}
+	b.handleMessage("nearbyitem:ammo,60,100") // nearer, but not what we were after
+	if item, ok := b.itemTarget(here, "ammo"); !ok || item.Loc != (Loc{X: 200, Y: 100}) {
+		t.Fatalf("went for %v, want the ammo we were interrupted on the way to", item)
+	}
+	if b.state.Interrupted == nil {
+		t.Fatal("forgot the interrupted ammo before reaching it")
+	}
+
+	// once it's gone, settle for the best we can see
+	clock.Advance(4 * time.Second)
+	b.handleMessage("nearbyitem:ammo,60,100")
+	b.expireItems()
+	if item, ok := b.itemTarget(here, "ammo"); !ok || item.Loc != (Loc{X: 60, Y: 100}) {
+		t.Errorf("went for %v once the interrupted ammo was gone, want the ammo at (60,100)", item)
+	}
+	if b.state.Interrupted != nil {
+		t.Errorf("still remembering %v after it vanished", b.state.Interrupted)
+	}
+}
+
+func TestRushExit(t *testing.T) {
+	tests := []struct {
+		name   string
+		rush   bool
+		hasKey string
+		locked bool
+		enemy  string
+		exit   bool
+	}{
+		{"off", false, "True", false, "", false},
+		{"with the key", true, "True", false, "", true},
+		{"enemy far off", true, "True", false, "nearbyplayer:orc,grunt,100,400", true},
+		{"enemy too close", true, "True", false, "nearbyplayer:orc,grunt,120,100", false},
+		{"exit open without a key", true, "False", false, "", true},
+		{"exit locked without a key", true, "False", true, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := newTestBot(func(cfg *Config) {
+				cfg.RushExit = tt.rush
+				cfg.PanicDistance = 50
+			})
+			b.exitLocked = tt.locked
+			// out of ammo, so we'd otherwise go looking for some
+			b.handleMessage("playerupdate:100,100,10,0," + tt.hasKey)
+			b.handleMessage("exit:300,100")
+			if tt.enemy != "" {
+				b.handleMessage(tt.enemy)
+			}
+			got := b.strategy.Decide(b.Snapshot()).Goal
+			if (got == "exit") != tt.exit {
+				t.Errorf("went for %s, want exit %v", got, tt.exit)
+			}
+		})
+	}
+}
+
+func TestStartDirection(t *testing.T) {
+	tests := []struct {
+		startDir string
+		mapped   bool
+		want     Loc // the sign of the first step on each axis
+	}{
+		{"sw", false, Loc{X: -1, Y: 1}},
+		{"nw", true, Loc{X: -1, Y: -1}},
+		{"auto", false, Loc{X: 1, Y: -1}}, // nothing to go on, so north east
+		{"auto", true, Loc{X: 1, Y: 1}},   // the room is all to the south east
+	}
+	for _, tt := range tests {
+		b, _ := newTestBot(func(cfg *Config) {
+			cfg.StartDir = tt.startDir
+			cfg.TickMs = minTickMs
+		})
+		conn := &recordingTransport{}
+		b.conn = conn
+		b.handleMessage("playerupdate:100,100,10,10,False")
+		if tt.mapped {
+			b.setWall(0, 0)
+			b.setWall(800, 800)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			b.writeLoop(ctx, time.Time{})
+		}()
+		var first Loc
+		for give := time.Now().Add(5 * time.Second); first == (Loc{}); time.Sleep(time.Millisecond) {
+			if time.Now().After(give) {
+				t.Fatalf("%s: never moved", tt.startDir)
+			}
+			for _, msg := range conn.sent() {
+				if params, ok := strings.CutPrefix(msg, "moveto:"); ok {
+					first = movetoLoc(params)
+					break
+				}
+			}
+		}
+		cancel()
+		<-done
+		step := Loc{X: sign(first.X - 100), Y: sign(first.Y - 100)}
+		if step != tt.want {
+			t.Errorf("%s, map known %v: first moved to %v, want a step of %v", tt.startDir, tt.mapped, first, tt.want)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func TestFightThroughToKey(t *testing.T) {
+	b, clock := newTestBot(nil)
+	// a one tile corridor, with our key at the far end
+	for i := 0; i <= 20; i++ {
+		b.setFloor(tileCentre(b, i), tileCentre(b, 0))
+	}
+	at := func(i int) Loc { return Loc{X: tileCentre(b, i), Y: tileCentre(b, 0)} }
+	b.handleMessage(fmt.Sprintf("playerupdate:%d,%d,10,10,False", at(0).X, at(0).Y))
+	b.handleMessage(fmt.Sprintf("nearbyitem:%s,%d,%d", b.myKeyName(), at(15).X, at(15).Y))
+	steps := []struct {
+		name  string
+		enemy int // the tile they're on, or -1 for out of sight
+		want  string
+	}{
+		{"nobody about", -1, "key"},
+		{"in the way", 8, "enemy"},
+		{"gone", -1, "key"},
+		{"beyond the key", 19, "key"},
+	}
+	for _, step := range steps {
+		clock.Advance(2 * time.Second) // long enough to forget the last sighting
+		if step.enemy >= 0 {
+			b.handleMessage(fmt.Sprintf("nearbyplayer:orc,grunt,%d,%d", at(step.enemy).X, at(step.enemy).Y))
+		}
+		if got := b.strategy.Decide(b.Snapshot()).Goal; got != step.want {
+			t.Errorf("%s: went for %s, want %s", step.name, got, step.want)
+		}
+	}
+}
+
+func TestNoteInterruption(t *testing.T) {
+	tests := []struct {
+		target      string
+		interrupted bool
+		keepHeading bool
+	}{
+		{"enemy", true, false},
+		{"kite", true, false},
+		{"flee", true, false},
+		{"dodge", true, false},
+		{"reposition", true, false},
+		{"ammo", false, true}, // still on our way
+		{"food", false, true},
+		{"key", false, false}, // dropped for something better, not interrupted
+		{"strafe", false, false},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBot(nil)
+		b.handleMessage("playerupdate:100,100,10,10,False")
+		b.handleMessage("nearbyitem:ammo,200,100")
+		b.handleMessage("nearbyplayer:orc,grunt,100,160")
+		heading, _ := b.itemTarget(b.selfLoc(), "ammo")
+		b.noteInterruption(tt.target)
+		if got := b.state.Interrupted != nil; got != tt.interrupted {
+			t.Errorf("%s: interrupted %v, want %v", tt.target, got, tt.interrupted)
+		}
+		if got := b.headingFor == heading; got != tt.keepHeading {
+			t.Errorf("%s: still heading for the ammo %v, want %v", tt.target, got, tt.keepHeading)
+		}
+	}
+}